@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/vivekschauhan/amplify-tool/pkg/tools/agentcache"
+
+	"github.com/spf13/cobra"
+)
+
+var agentCacheCfg = &agentcache.Config{}
+
+func newCleanAgentCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cleanAgentCache",
+		Short:   "Amplify Agent Cache Cleanup Tool",
+		Version: "0.0.1",
+		RunE:    runCleanAgentCache,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViperConfig(cmd)
+			if err != nil {
+				return err
+			}
+			err = v.Unmarshal(agentCacheCfg)
+			if err != nil {
+				return err
+			}
+
+			agentCacheCfg.Config = *cfg
+			return nil
+		},
+	}
+
+	initCleanAgentCacheCmdFlags(cmd)
+
+	return cmd
+}
+
+func initCleanAgentCacheCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().String("log_level", "info", "log level")
+	cmd.Flags().String("log_format", "json", "line or json")
+	cmd.Flags().Bool("dry_run", false, "Report the number of cache entries to remove without writing any files")
+	cmd.Flags().String("cache_file", "", "The agent persistent cache file, found in the data/cache directory of the agent")
+	cmd.Flags().String("out_file", "", "The name of the file to save the cleaned cache to, defaults to the cache_file")
+	cmd.Flags().String("cache_backup_file", "", "The name of the file to backup the original cache to, defaults to the cache_file with a .bak suffix")
+	cmd.Flags().String("plan_file", "", "The plan_file of the duplicate tool listing the deleted services, required")
+	cmd.Flags().String("backup_file", "", "The backup_file of the duplicate tool holding the IDs of the deleted services")
+}
+
+func runCleanAgentCache(_ *cobra.Command, _ []string) error {
+	tool := agentcache.NewTool(agentCacheCfg)
+	return tool.Run()
+}
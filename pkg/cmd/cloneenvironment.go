@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/vivekschauhan/amplify-tool/pkg/tools/clone"
+
+	"github.com/spf13/cobra"
+)
+
+var cloneCfg = &clone.Config{}
+
+func newCloneEnvironmentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cloneEnvironment",
+		Short:   "Amplify Environment Clone Tool",
+		Version: "0.0.1",
+		RunE:    runCloneEnvironment,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViperConfig(cmd)
+			if err != nil {
+				return err
+			}
+			err = v.Unmarshal(cloneCfg)
+			if err != nil {
+				return err
+			}
+
+			cloneCfg.Config = *cfg
+			return nil
+		},
+	}
+
+	initCloneEnvironmentCmdFlags(cmd)
+
+	return cmd
+}
+
+func initCloneEnvironmentCmdFlags(cmd *cobra.Command) {
+	baseFlags(cmd)
+	cmd.Flags().String("source", "", "The name of the environment to clone")
+	cmd.Flags().String("target", "", "The name of the environment to clone to, created when it does not exist")
+	cmd.Flags().Bool("clone_assets", false, "Also clone the assets of the source environment, named with the target environment appended")
+}
+
+func runCloneEnvironment(_ *cobra.Command, _ []string) error {
+	tool := clone.NewTool(cloneCfg)
+	return tool.Run()
+}
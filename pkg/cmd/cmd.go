@@ -24,10 +24,16 @@ func NewRootCmd() *cobra.Command {
 
 	rootCmd := &cobra.Command{Use: ""}
 	rootCmd.AddCommand(newRepairCmd())
+	rootCmd.AddCommand(newRollbackCmd())
 	rootCmd.AddCommand(newRepairProductCmd())
 	rootCmd.AddCommand(newDuplicateCmd())
+	rootCmd.AddCommand(newRestoreDuplicatesCmd())
+	rootCmd.AddCommand(newDedupeRevisionsCmd())
+	rootCmd.AddCommand(newCleanAgentCacheCmd())
 	rootCmd.AddCommand(newExportCmd())
 	rootCmd.AddCommand(newImportCmd())
+	rootCmd.AddCommand(newVerifyCmd())
+	rootCmd.AddCommand(newCloneEnvironmentCmd())
 	rootCmd.AddCommand(newMetricCmd())
 	return rootCmd
 }
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/vivekschauhan/amplify-tool/pkg/tools/revisions"
+
+	"github.com/spf13/cobra"
+)
+
+var revisionsCfg = &revisions.Config{}
+
+func newDedupeRevisionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "dedupeRevisions",
+		Short:   "Amplify Revision Deduplication Tool",
+		Version: "0.0.1",
+		RunE:    runDedupeRevisions,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViperConfig(cmd)
+			if err != nil {
+				return err
+			}
+			err = v.Unmarshal(revisionsCfg)
+			if err != nil {
+				return err
+			}
+
+			revisionsCfg.Config = *cfg
+			return nil
+		},
+	}
+
+	initDedupeRevisionsCmdFlags(cmd)
+
+	return cmd
+}
+
+func initDedupeRevisionsCmdFlags(cmd *cobra.Command) {
+	baseFlags(cmd)
+	cmd.Flags().String("environments", "", "The environments to remove identical revisions from, comma separated")
+	cmd.Flags().String("plan_file", "revision-plan.json", "The name of the file to save the plan to")
+	cmd.Flags().String("backup_file", "", "The name of the file to backup the deleted revisions to, required to execute, restore them with restoreDuplicates")
+	cmd.Flags().Bool("execute", false, "Delete the revisions and update the specHashes of the services, after writing the backup_file")
+}
+
+func runDedupeRevisions(_ *cobra.Command, _ []string) error {
+	tool := revisions.NewTool(revisionsCfg)
+	return tool.Run()
+}
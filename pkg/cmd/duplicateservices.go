@@ -39,6 +39,14 @@ func initDuplicateCmdFlags(cmd *cobra.Command) {
 	cmd.Flags().String("out_file", "", "The name of the file to save to")
 	cmd.Flags().String("backup_file", "", "The name of the file to backup to, not created in dry runs")
 	cmd.Flags().String("environments", "", "The environments to run the deduplication against, comma separated")
+	cmd.Flags().Bool("cross_environment", false, "Also group services across all of the environments to find APIs discovered into several of them")
+	cmd.Flags().String("group_by", "", "How to group duplicate services (externalAPIID, primaryKey, title, endpoint, specHash), joined with + for a composite key")
+	cmd.Flags().String("plan_file", "", "The name of the file to save the plan to, as yaml for a .yaml or .yml file and json otherwise")
+	cmd.Flags().String("from_plan", "", "A plan file, with only the approved actions, to follow rather than finding duplicates")
+	cmd.Flags().String("script_format", "", "Also write the commands as a runnable script (bash, powershell)")
+	cmd.Flags().String("script_file", "", "The name of the file to save the script to, defaults to duplicate-cleanup.sh or duplicate-cleanup.ps1")
+	cmd.Flags().String("html_report", "", "The name of the file to save a standalone html report of the duplicates to")
+	cmd.Flags().Bool("execute", false, "Execute the actions through the API, after writing the backup_file, rather than only writing the commands")
 }
 
 func runDeduplicate(_ *cobra.Command, _ []string) error {
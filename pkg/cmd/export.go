@@ -37,7 +37,18 @@ func newExportCmd() *cobra.Command {
 func initExportCmdFlags(cmd *cobra.Command) {
 	baseFlags(cmd)
 	cmd.Flags().String("environment", "", "The environment name to export")
+	cmd.Flags().String("environments", "", "The environment names or glob patterns to export, comma separated")
+	cmd.Flags().Bool("per_environment", false, "Write one file per environment, named after the out_file with the environment name appended")
 	cmd.Flags().String("out_file", "export.json", "The name of the file to save to")
+	cmd.Flags().Bool("extract_specs", false, "Write the API specs of revisions to files in the specs_dir and reference them from the export")
+	cmd.Flags().String("specs_dir", "specs", "The directory, relative to the out_file, to write the extracted API specs to")
+	cmd.Flags().Bool("keep_owners", false, "Keep the owning team of the resources and write the org teams to the teams_file")
+	cmd.Flags().String("teams_file", "teams.json", "The name of the file to save the org teams to when keeping owners")
+	cmd.Flags().String("since_file", "", "A previous export, only the resources created, updated or deleted since it are written")
+	cmd.Flags().String("query", "", "An RSQL query the exported APIServices and Assets must match")
+	cmd.Flags().String("tags", "", "The tags the exported APIServices and Assets must have, comma separated")
+	cmd.Flags().String("attributes", "", "The attributes the exported APIServices and Assets must have as key=value pairs, comma separated")
+	cmd.Flags().String("format", "json", "The output format (json, yaml, dir), dir writes a directory of <kind>/<scope kind>/<scope>/<name>.yaml files")
 }
 
 func runExport(_ *cobra.Command, _ []string) error {
@@ -1,25 +1,65 @@
 package cmd
 
 import (
-	"fmt"
+	"time"
+
+	"github.com/vivekschauhan/amplify-tool/pkg/tools/importer"
 
 	"github.com/spf13/cobra"
 )
 
+var importCfg = &importer.Config{}
+
 func newImportCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "import",
 		Short:   "Amplify Import Tool",
-		Version: "0.0.1",
+		Version: "0.0.2",
 		RunE:    runImport,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViperConfig(cmd)
+			if err != nil {
+				return err
+			}
+			err = v.Unmarshal(importCfg)
+			if err != nil {
+				return err
+			}
+
+			importCfg.Config = *cfg
 			return nil
 		},
 	}
+
+	initImportCmdFlags(cmd)
+
 	return cmd
 }
 
+func initImportCmdFlags(cmd *cobra.Command) {
+	baseFlags(cmd)
+	cmd.Flags().String("in_file", "export.json", "The export file, json or yaml, or directory to import")
+	cmd.Flags().String("results_file", "import-results.json", "The name of the file to save the per resource import results to")
+	cmd.Flags().String("manifest_file", "", "The manifest of the export, defaults to the manifest written next to the in_file")
+	cmd.Flags().Bool("force", false, "Import even when the in_file has no manifest or does not match it")
+	cmd.Flags().String("on_conflict", "overwrite", "What to do when a resource already exists in the target (skip, overwrite, rename, fail)")
+	cmd.Flags().String("rename_suffix", "-imported", "The suffix added to the name of existing resources with the rename conflict policy")
+	cmd.Flags().Bool("keep_owners", false, "Keep the owning team of the resources, remapped to the matching team in the target org")
+	cmd.Flags().String("team_map_file", "", "The path of a json file mapping source team IDs or names to target team IDs or names")
+	cmd.Flags().String("teams_file", "", "The path of the teams file written by export, used to match teams by name")
+	cmd.Flags().String("target_org_id", "", "The Amplify org ID to import into, when different to the org_id")
+	cmd.Flags().String("target_region", "", "The central region (us, eu, apac) of the target org")
+	cmd.Flags().String("target_url", "", "The central URL of the target org")
+	cmd.Flags().String("target_auth.private_key", "./target_private_key.pem", "The private key associated with the target org service account")
+	cmd.Flags().String("target_auth.public_key", "./target_public_key.pem", "The public key associated with the target org service account")
+	cmd.Flags().String("target_auth.key_password", "", "The password for the target org private key")
+	cmd.Flags().String("target_auth.url", "", "The AxwayID auth URL for the target org")
+	cmd.Flags().String("target_auth.client_id", "", "The target org service account client ID")
+	cmd.Flags().Duration("target_auth.timeout", 10*time.Second, "The connection timeout for AxwayID for the target org")
+	cmd.Flags().String("env_map", "", "The environments to remap on import as source=target pairs, comma separated")
+}
+
 func runImport(_ *cobra.Command, _ []string) error {
-	fmt.Printf("To import an export use the axway cli command `axway central applly -f export-file.json`")
-	return nil
+	tool := importer.NewTool(importCfg)
+	return tool.Run()
 }
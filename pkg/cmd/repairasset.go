@@ -40,6 +40,11 @@ func initRepairCmdFlags(cmd *cobra.Command) {
 	baseFlags(cmd)
 	cmd.Flags().String("service_mapping_file", "", "The path of the service mapping file")
 	cmd.Flags().String("product_catalog_file", "", "The path of the product-catalog.json")
+	cmd.Flags().String("assets", "", "The names or IDs of the assets to repair, comma separated, all assets in error when not set")
+	cmd.Flags().String("exclude_assets", "", "The names or IDs of assets in error to leave alone, comma separated")
+	cmd.Flags().String("journal", "asset-repair-journal.jsonl", "The file to record every change made by the repair in, for rollback")
+	cmd.Flags().Bool("append_journal", false, "Append to an existing journal, a rollback of it then also reverses the earlier runs")
+	cmd.Flags().String("error_match", "", "Only repair assets with a status message matching this regular expression")
 }
 
 func runRepairAsset(_ *cobra.Command, _ []string) error {
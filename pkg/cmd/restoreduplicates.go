@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/vivekschauhan/amplify-tool/pkg/tools/restore"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreCfg = &restore.Config{}
+
+func newRestoreDuplicatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "restoreDuplicates",
+		Short:   "Amplify Duplicate Restore Tool",
+		Version: "0.0.1",
+		RunE:    runRestoreDuplicates,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViperConfig(cmd)
+			if err != nil {
+				return err
+			}
+			err = v.Unmarshal(restoreCfg)
+			if err != nil {
+				return err
+			}
+
+			restoreCfg.Config = *cfg
+			return nil
+		},
+	}
+
+	initRestoreDuplicatesCmdFlags(cmd)
+
+	return cmd
+}
+
+func initRestoreDuplicatesCmdFlags(cmd *cobra.Command) {
+	baseFlags(cmd)
+	cmd.Flags().String("backup_file", "", "The backup file written by the duplicate or dedupeRevisions tool")
+	cmd.MarkFlagRequired("backup_file")
+	cmd.Flags().String("actions", "", "The IDs of the actions to restore, comma separated, all actions in the backup when not set")
+}
+
+func runRestoreDuplicates(_ *cobra.Command, _ []string) error {
+	tool := restore.NewTool(restoreCfg)
+	return tool.Run()
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/vivekschauhan/amplify-tool/pkg/tools/rollback"
+
+	"github.com/spf13/cobra"
+)
+
+var rollbackCfg = &rollback.Config{}
+
+func newRollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rollback",
+		Short:   "Amplify Rollback Tool",
+		Version: "0.0.1",
+		RunE:    runRollback,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViperConfig(cmd)
+			if err != nil {
+				return err
+			}
+			err = v.Unmarshal(rollbackCfg)
+			if err != nil {
+				return err
+			}
+
+			rollbackCfg.Config = *cfg
+			return nil
+		},
+	}
+
+	initRollbackCmdFlags(cmd)
+
+	return cmd
+}
+
+func initRollbackCmdFlags(cmd *cobra.Command) {
+	baseFlags(cmd)
+	cmd.Flags().String("journal", "", "The journal of the changes to roll back, written by repairAsset")
+}
+
+func runRollback(_ *cobra.Command, _ []string) error {
+	tool := rollback.NewTool(rollbackCfg)
+	return tool.Run()
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/vivekschauhan/amplify-tool/pkg/tools/verify"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyCfg = &verify.Config{}
+
+func newVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "verify",
+		Short:   "Amplify Export Verify Tool",
+		Version: "0.0.1",
+		RunE:    runVerify,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViperConfig(cmd)
+			if err != nil {
+				return err
+			}
+			err = v.Unmarshal(verifyCfg)
+			if err != nil {
+				return err
+			}
+
+			verifyCfg.Config = *cfg
+			return nil
+		},
+	}
+
+	initVerifyCmdFlags(cmd)
+
+	return cmd
+}
+
+func initVerifyCmdFlags(cmd *cobra.Command) {
+	cmd.Flags().String("log_level", "info", "log level")
+	cmd.Flags().String("log_format", "json", "line or json")
+	cmd.Flags().String("in_file", "export.json", "The export file or directory to verify")
+	cmd.Flags().String("manifest_file", "", "The manifest of the export, defaults to the manifest written next to the in_file")
+}
+
+func runVerify(_ *cobra.Command, _ []string) error {
+	tool := verify.NewTool(verifyCfg)
+	return tool.Run()
+}
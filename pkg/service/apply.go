@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Axway/agent-sdk/pkg/apic"
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	"github.com/Axway/agent-sdk/pkg/apic/definitions"
+)
+
+// kindOrder is the order resources are applied in, dependencies first
+var kindOrder = []string{
+	management.APIServiceGVK().Kind,
+	management.APIServiceRevisionGVK().Kind,
+	management.AccessRequestDefinitionGVK().Kind,
+	management.CredentialRequestDefinitionGVK().Kind,
+	management.APIServiceInstanceGVK().Kind,
+	catalog.AssetGVK().Kind,
+	catalog.AssetMappingGVK().Kind,
+	catalog.ProductGVK().Kind,
+	catalog.ReleaseTagGVK().Kind,
+	catalog.ProductPlanGVK().Kind,
+	catalog.QuotaGVK().Kind,
+	catalog.ResourceGVK().Kind,
+	catalog.DocumentGVK().Kind,
+}
+
+func kindIndex(kind string) int {
+	for i, k := range kindOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(kindOrder)
+}
+
+// IsApplyKind returns true when resources of the kind can be applied
+func IsApplyKind(kind string) bool {
+	return kindIndex(kind) < len(kindOrder)
+}
+
+// SortByKind orders the resources so that each resource is applied after the resources it depends on
+func SortByKind(resources []*v1.ResourceInstance) []*v1.ResourceInstance {
+	sorted := make([]*v1.ResourceInstance, len(resources))
+	copy(sorted, resources)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return kindIndex(sorted[i].Kind) < kindIndex(sorted[j].Kind)
+	})
+	return sorted
+}
+
+// ApplyResource creates, or updates when it exists, the resource along with its x-agent-details sub resource
+func ApplyResource(client apic.Client, ri *v1.ResourceInstance, exists bool) error {
+	var applied *v1.ResourceInstance
+	var err error
+	if exists {
+		applied, err = client.UpdateResourceInstance(ri)
+	} else {
+		applied, err = client.CreateResourceInstance(ri)
+	}
+	if err != nil {
+		return err
+	}
+
+	if details := ri.GetSubResource(definitions.XAgentDetails); details != nil {
+		err = client.CreateSubResource(applied.ResourceMeta, map[string]interface{}{definitions.XAgentDetails: details})
+		if err != nil {
+			return fmt.Errorf("unable to apply x-agent-details: %w", err)
+		}
+	}
+	return nil
+}
+
+// ExistingResource returns the resource with the self link of ri, or nil when it does not exist. Every error other
+// than not found is returned, so that a failed read is not taken for a missing resource.
+func ExistingResource(client apic.Client, ri *v1.ResourceInstance) (*v1.ResourceInstance, error) {
+	existing, err := client.GetResource(ri.GetSelfLink())
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return existing, nil
+}
+
+// isNotFound returns true for the error of a request that the server answered with not found, the client only
+// reports the status in the error message
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), "status - 404")
+}
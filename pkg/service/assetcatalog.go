@@ -22,8 +22,10 @@ type AssetCatalog interface {
 	RepairAsset()
 	PostRepairAsset()
 	GetAssetInfo(logger *logrus.Entry, id string) AssetInfo
+	FindAsset(name string) *catalog.Asset
 	FindAssetResource(logger *logrus.Entry, nameWithScope string) string
 	AssetsForInstance(group, env, instance string) []string
+	AssetsForRevision(group, env, revision string) []string
 }
 
 type assetCatalog struct {
@@ -32,12 +34,18 @@ type assetCatalog struct {
 	Assets                map[string]AssetInfo
 	AssetResourcesMap     map[string]string
 	InstanceToResourceMap map[string][]string
+	RevisionToResourceMap map[string][]string
 	resourceLock          sync.Mutex
 	serviceRegistry       ServiceRegistry
+	filter                *ResourceFilter
+	repairSelector        *RepairSelector
+	journal               *Journal
 	filterUsingRegistry   bool
 	assetRelRes           bool
 	forExport             bool
+	exportResources       bool
 	stripData             bool
+	keepOwners            bool
 	dryRun                bool
 }
 
@@ -48,6 +56,7 @@ func NewAssetCatalog(logger *logrus.Logger, apicClient apic.Client, dryRun bool,
 		Assets:                make(map[string]AssetInfo),
 		AssetResourcesMap:     make(map[string]string),
 		InstanceToResourceMap: make(map[string][]string),
+		RevisionToResourceMap: make(map[string][]string),
 		resourceLock:          sync.Mutex{},
 		serviceRegistry:       serviceRegistry,
 		dryRun:                dryRun,
@@ -78,12 +87,47 @@ func ForExport() assetCatalogOpt {
 	}
 }
 
+// WithExportAssetResources also reads the asset resources of the assets and their releases when reading for export
+func WithExportAssetResources() assetCatalogOpt {
+	return func(a *assetCatalog) {
+		a.exportResources = true
+		a.assetRelRes = true
+	}
+}
+
 func StripData() assetCatalogOpt {
 	return func(a *assetCatalog) {
 		a.stripData = true
 	}
 }
 
+func WithAssetOwners(keepOwners bool) assetCatalogOpt {
+	return func(a *assetCatalog) {
+		a.keepOwners = keepOwners
+	}
+}
+
+// WithAssetFilter only reads the Assets matching the filter, and their mappings
+func WithAssetFilter(filter *ResourceFilter) assetCatalogOpt {
+	return func(a *assetCatalog) {
+		a.filter = filter
+	}
+}
+
+// WithRepairSelector only repairs the assets in error selected by the selector
+func WithRepairSelector(selector *RepairSelector) assetCatalogOpt {
+	return func(a *assetCatalog) {
+		a.repairSelector = selector
+	}
+}
+
+// WithJournal records the changes made by the asset repair in the journal
+func WithJournal(journal *Journal) assetCatalogOpt {
+	return func(a *assetCatalog) {
+		a.journal = journal
+	}
+}
+
 func (t *assetCatalog) WriteAssets() {
 	SaveToFile(t.logger, "asset-catalog", "asset-catalog.json", t.Assets)
 }
@@ -116,17 +160,7 @@ func (t *assetCatalog) GetAssetOutput() []v1.Interface {
 					ri, _ = a.AsInstance()
 				}
 
-				ri.Metadata.Audit = v1.AuditMetadata{}
-				ri.Metadata.References = []v1.Reference{}
-				ri.Metadata.ID = ""
-				ri.Metadata.ResourceVersion = ""
-				ri.Metadata.Scope.ID = ""
-				ri.Metadata.Scope.SelfLink = ""
-				ri.Metadata.SelfLink = ""
-				ri.Owner = nil
-				ri.Tags = []string{}
-				ri.Attributes = map[string]string{}
-				ri.Finalizers = make([]v1.Finalizer, 0)
+				stripResource(ri, t.keepOwners)
 				cleanObjs = append(cleanObjs, ri)
 			}(obj)
 		}
@@ -149,9 +183,7 @@ func (t *assetCatalog) ReadAssets(repairProduct bool) error {
 			validEnvs[d] = struct{}{}
 			go func(env string) {
 				defer wg.Done()
-				params := map[string]string{
-					"query": fmt.Sprintf("metadata.references.name==%s", env),
-				}
+				params := t.filter.params(fmt.Sprintf("metadata.references.name==%s", env))
 				envAssets, err := t.apicClient.GetAPIV1ResourceInstances(params, a.GetKindLink())
 				if err != nil {
 					t.logger.WithError(err).Error("unable to read assets")
@@ -164,7 +196,7 @@ func (t *assetCatalog) ReadAssets(repairProduct bool) error {
 		wg.Wait()
 	} else {
 		var err error
-		assets, err = t.apicClient.GetAPIV1ResourceInstances(nil, a.GetKindLink())
+		assets, err = t.apicClient.GetAPIV1ResourceInstances(t.filter.params(""), a.GetKindLink())
 		if err != nil {
 			t.logger.WithError(err).Error("unable to read assets")
 		}
@@ -184,12 +216,17 @@ func (t *assetCatalog) ReadAssets(repairProduct bool) error {
 				ca := catalog.NewAsset("")
 				ca.FromInstance(in)
 				logger := t.logger.WithField("asset", in.Name)
-				lock.Lock()
-				defer lock.Unlock()
-				t.Assets[in.Metadata.ID] = AssetInfo{
+				assetInfo := AssetInfo{
 					Asset:         ca,
 					AssetMappings: t.readAssetMappings(logger, in.Name, validEnvs),
 				}
+				if t.exportResources {
+					assetInfo.AssetResources = t.readAssetResources(logger, in.Name, catalog.AssetGVK().Kind, in.Metadata.ID)
+					assetInfo.AssetReleases = t.readAssetReleases(logger, in.Metadata.ID)
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				t.Assets[in.Metadata.ID] = assetInfo
 			}()
 			limiter <- d
 		}
@@ -329,6 +366,10 @@ func (t *assetCatalog) readAssetMappings(logger *logrus.Entry, scopeName string,
 		}
 		apiRevParts := strings.Split(a.Spec.Inputs.ApiServiceRevision, "/")
 		svcInfo := t.serviceRegistry.GetAPIServiceInfo(apiSvcParts[1], apiSvcParts[2])
+		if svcInfo == nil {
+			// the service is not being exported
+			continue
+		}
 
 		// check that the revision is being exported
 		found := false
@@ -364,6 +405,9 @@ func (t *assetCatalog) readAssetResources(logger *logrus.Entry, scopeName, scope
 		t.resourceLock.Lock()
 		t.AssetResourcesMap[assetResourceMapKey(scopeName, ar.Name)] = scopeID
 		t.InstanceToResourceMap[ar.References.ApiServiceInstance] = append(t.InstanceToResourceMap[ar.References.ApiServiceInstance], assetResourceMapKey(scopeName, ar.Name))
+		if ar.References.ApiServiceRevision != "" {
+			t.RevisionToResourceMap[ar.References.ApiServiceRevision] = append(t.RevisionToResourceMap[ar.References.ApiServiceRevision], assetResourceMapKey(scopeName, ar.Name))
+		}
 		t.resourceLock.Unlock()
 		logger.
 			WithField("assetResource", ar.Name).
@@ -391,6 +435,15 @@ func (t *assetCatalog) GetAssetInfo(logger *logrus.Entry, id string) AssetInfo {
 	return AssetInfo{}
 }
 
+func (t *assetCatalog) FindAsset(name string) *catalog.Asset {
+	for _, assetInfo := range t.Assets {
+		if assetInfo.Asset.Name == name {
+			return assetInfo.Asset
+		}
+	}
+	return nil
+}
+
 func (t *assetCatalog) AssetsForInstance(group, env, instance string) []string {
 	instancePath := fmt.Sprintf("%s/%s/%s", group, env, instance)
 	if assets, found := t.InstanceToResourceMap[instancePath]; found {
@@ -399,9 +452,14 @@ func (t *assetCatalog) AssetsForInstance(group, env, instance string) []string {
 	return nil
 }
 
+// AssetsForRevision returns the scope/name of the asset resources, of assets and asset releases, referencing the revision
+func (t *assetCatalog) AssetsForRevision(group, env, revision string) []string {
+	return t.RevisionToResourceMap[fmt.Sprintf("%s/%s/%s", group, env, revision)]
+}
+
 func (t *assetCatalog) RepairAsset() {
 	for _, asset := range t.Assets {
-		if asset.Asset.Status != nil && asset.Asset.Status.Level == "Error" {
+		if t.repairSelector.Selects(asset.Asset) {
 			logger := t.logger.
 				WithField("assetID", asset.Asset.Metadata.ID).
 				WithField("assetName", asset.Asset.Name)
@@ -469,7 +527,7 @@ func (t *assetCatalog) getAssetReleaseForReleaseTag(releaseTagID string) *catalo
 
 func (t *assetCatalog) PostRepairAsset() {
 	for _, asset := range t.Assets {
-		if asset.Asset.Status != nil && asset.Asset.Status.Level == "Error" {
+		if t.repairSelector.Selects(asset.Asset) {
 			logger := t.logger.
 				WithField("assetID", asset.Asset.Metadata.ID).
 				WithField("assetName", asset.Asset.Name)
@@ -491,6 +549,8 @@ func (t *assetCatalog) deleteAssetResources(logger *logrus.Entry, asset AssetInf
 			err := t.apicClient.DeleteResourceInstance(assetResource.AssetResource)
 			if err != nil {
 				logger.WithError(err).Error("Unable to delete the corrupted asset resource")
+			} else {
+				t.journal.Deleted(assetResource.AssetResource)
 			}
 			key := assetResourceMapKey(asset.Asset.Name, assetResource.AssetResource.Name)
 			delete(t.AssetResourcesMap, key)
@@ -525,6 +585,8 @@ func (t *assetCatalog) createAssetMapping(logger *logrus.Entry, assetName string
 			ri, err = t.apicClient.CreateResourceInstance(am)
 			if err != nil {
 				logger.WithError(err).Error("unable to create new asset mapping")
+			} else {
+				t.journal.Created(ri)
 			}
 		}
 		if err == nil {
@@ -593,6 +655,7 @@ func (t *assetCatalog) setAssetToDraft(logger *logrus.Entry, asset AssetInfo) er
 			logger.WithError(statusErr).Error("unable to transition asset to draft")
 			return statusErr
 		}
+		t.journal.StateChanged(asset.Asset, asset.Asset.State, string(catalog.AssetStateDRAFT))
 	}
 	return nil
 }
@@ -612,6 +675,7 @@ func (t *assetCatalog) createAssetRelease(logger *logrus.Entry, asset AssetInfo)
 		logger.WithError(err).Errorf("unable to create new release tag for asset: %s", asset.Asset.Name)
 		return nil, err
 	}
+	t.journal.Created(releaseTagRI)
 	return releaseTagRI, err
 }
 
@@ -632,6 +696,7 @@ func (t *assetCatalog) deprecatePreviousAssetRelease(logger *logrus.Entry, asset
 					logger.WithError(statusErr).Error("error deprecating AssetRelease")
 					break
 				}
+				t.journal.StateChanged(releaseTag, releaseTag.State, string(catalog.ProductStateDEPRECATED))
 				releaseTag.State = string(catalog.AssetStateDEPRECATED)
 			}
 		}
@@ -655,6 +720,7 @@ func (t *assetCatalog) archivePreviousAssetRelease(logger *logrus.Entry, assetRe
 					logger.WithError(statusErr).Error("error archiving AssetRelease")
 					break
 				}
+				t.journal.StateChanged(releaseTag, releaseTag.State, string(catalog.ProductStateARCHIVED))
 			}
 		}
 	}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Changeset holds the resources created, updated and deleted since a previous export
+type Changeset struct {
+	Create []v1.Interface `json:"create"`
+	Update []v1.Interface `json:"update"`
+	Delete []v1.Interface `json:"delete"`
+}
+
+// ResourceKey returns a key that is unique for the resource within an org
+func ResourceKey(ri *v1.ResourceInstance) string {
+	return fmt.Sprintf("%s/%s/%s/%s", ri.Metadata.Scope.Kind, ri.Metadata.Scope.Name, ri.Kind, ri.Name)
+}
+
+// ResourceHash returns the SHA-256 of the resource content, independent of the field order in its source
+func ResourceHash(res v1.Interface) (string, error) {
+	obj, err := toGeneric(res)
+	if err != nil {
+		return "", err
+	}
+	buf, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NewChangeset compares the current resources to the previous export using a hash of each resource
+func NewChangeset(previous []*v1.ResourceInstance, current []v1.Interface) (*Changeset, error) {
+	previousHashes := map[string]string{}
+	previousResources := map[string]*v1.ResourceInstance{}
+	for _, ri := range previous {
+		hash, err := ResourceHash(ri)
+		if err != nil {
+			return nil, err
+		}
+		previousHashes[ResourceKey(ri)] = hash
+		previousResources[ResourceKey(ri)] = ri
+	}
+
+	cs := &Changeset{
+		Create: []v1.Interface{},
+		Update: []v1.Interface{},
+		Delete: []v1.Interface{},
+	}
+	for _, res := range current {
+		ri, err := res.AsInstance()
+		if err != nil {
+			return nil, err
+		}
+		key := ResourceKey(ri)
+		previousHash, found := previousHashes[key]
+		delete(previousResources, key)
+		if !found {
+			cs.Create = append(cs.Create, res)
+			continue
+		}
+		hash, err := ResourceHash(ri)
+		if err != nil {
+			return nil, err
+		}
+		if hash != previousHash {
+			cs.Update = append(cs.Update, res)
+		}
+	}
+
+	// anything left in the previous export was removed
+	keys := []string{}
+	for key := range previousResources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cs.Delete = append(cs.Delete, previousResources[key])
+	}
+	return cs, nil
+}
+
+// SaveChangeset writes the changeset in the format, a dir changeset has a create, update and delete directory
+func SaveChangeset(logger *logrus.Logger, fileName, format string, cs *Changeset) error {
+	switch format {
+	case "", FormatJSON:
+		SaveToFile(logger, "changeset", fileName, cs)
+		return nil
+	case FormatYAML:
+		obj, err := toGeneric(cs)
+		if err != nil {
+			return err
+		}
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(fileName, buf, 0777)
+	case FormatDir:
+		if err := checkEmptyDir(fileName); err != nil {
+			return err
+		}
+		sections := cs.sections()
+		for section, resources := range sections {
+			if err := saveResourcesToDir(filepath.Join(fileName, section), resources); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %s, expected one of %s, %s or %s", format, FormatJSON, FormatYAML, FormatDir)
+}
+
+// sections returns the resources of the changeset by section
+func (cs *Changeset) sections() map[string][]v1.Interface {
+	return map[string][]v1.Interface{
+		"create": cs.Create,
+		"update": cs.Update,
+		"delete": cs.Delete,
+	}
+}
+
+// changesetSections are the sections of a changeset, which are also the directories of a dir changeset
+var changesetSections = []string{"create", "update", "delete"}
+
+// ReadChangeset reads the resources of each section of a changeset written by SaveChangeset
+func ReadChangeset(fileName, format string) (map[string][]*v1.ResourceInstance, error) {
+	sections := map[string][]*v1.ResourceInstance{}
+	if format == FormatDir {
+		for _, section := range changesetSections {
+			dir := filepath.Join(fileName, section)
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				continue
+			}
+			resources, err := ReadResources(dir)
+			if err != nil {
+				return nil, err
+			}
+			sections[section] = resources
+		}
+		return sections, nil
+	}
+
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatYAML {
+		var obj interface{}
+		if err := yaml.Unmarshal(buf, &obj); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", fileName, err)
+		}
+		if buf, err = json.Marshal(obj); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", fileName, err)
+		}
+	}
+	if err := json.Unmarshal(buf, &sections); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", fileName, err)
+	}
+	return sections, nil
+}
+
+// IsChangeset returns true when the file or directory holds a changeset written by SaveChangeset rather than resources
+func IsChangeset(fileName string) (bool, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir() {
+		for _, section := range changesetSections {
+			if _, err := os.Stat(filepath.Join(fileName, section)); err == nil {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return false, err
+	}
+	// a changeset is a single object with the sections, resources are a json array or yaml documents with a kind
+	var obj map[string]interface{}
+	if isYAMLFile(fileName) {
+		if err := yaml.Unmarshal(buf, &obj); err != nil {
+			return false, nil
+		}
+	} else if err := json.Unmarshal(buf, &obj); err != nil {
+		return false, nil
+	}
+	if _, found := obj["kind"]; found {
+		return false, nil
+	}
+	for _, section := range changesetSections {
+		if _, found := obj[section]; found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
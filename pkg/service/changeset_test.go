@@ -0,0 +1,95 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+)
+
+func testService(t *testing.T, env, name, title string) *v1.ResourceInstance {
+	t.Helper()
+	svc := management.NewAPIService(name, env)
+	svc.Title = title
+	ri, err := svc.AsInstance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ri
+}
+
+func resourceNames(resources []v1.Interface) []string {
+	names := []string{}
+	for _, res := range resources {
+		names = append(names, res.GetName())
+	}
+	return names
+}
+
+func TestNewChangeset(t *testing.T) {
+	tests := []struct {
+		name       string
+		previous   []*v1.ResourceInstance
+		current    []*v1.ResourceInstance
+		wantCreate []string
+		wantUpdate []string
+		wantDelete []string
+	}{
+		{
+			name:     "no changes",
+			previous: []*v1.ResourceInstance{testService(t, "env", "a", "A")},
+			current:  []*v1.ResourceInstance{testService(t, "env", "a", "A")},
+		},
+		{
+			name:       "created",
+			previous:   []*v1.ResourceInstance{testService(t, "env", "a", "A")},
+			current:    []*v1.ResourceInstance{testService(t, "env", "a", "A"), testService(t, "env", "b", "B")},
+			wantCreate: []string{"b"},
+		},
+		{
+			name:       "updated",
+			previous:   []*v1.ResourceInstance{testService(t, "env", "a", "A")},
+			current:    []*v1.ResourceInstance{testService(t, "env", "a", "changed")},
+			wantUpdate: []string{"a"},
+		},
+		{
+			name:       "deleted in key order",
+			previous:   []*v1.ResourceInstance{testService(t, "env", "c", "C"), testService(t, "env", "a", "A"), testService(t, "env", "b", "B")},
+			current:    []*v1.ResourceInstance{testService(t, "env", "b", "B")},
+			wantDelete: []string{"a", "c"},
+		},
+		{
+			name:       "same name in another environment",
+			previous:   []*v1.ResourceInstance{testService(t, "env", "a", "A")},
+			current:    []*v1.ResourceInstance{testService(t, "other", "a", "A")},
+			wantCreate: []string{"a"},
+			wantDelete: []string{"a"},
+		},
+		{
+			name:       "no previous export",
+			current:    []*v1.ResourceInstance{testService(t, "env", "a", "A")},
+			wantCreate: []string{"a"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			current := []v1.Interface{}
+			for _, ri := range tc.current {
+				current = append(current, ri)
+			}
+			cs, err := NewChangeset(tc.previous, current)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			for section, want := range map[string][]string{"create": tc.wantCreate, "update": tc.wantUpdate, "delete": tc.wantDelete} {
+				if want == nil {
+					want = []string{}
+				}
+				if got := resourceNames(cs.sections()[section]); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: got %v, want %v", section, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResourceFilter selects the APIServices and Assets to read using a server side RSQL query
+type ResourceFilter struct {
+	Query      string
+	Tags       []string
+	Attributes map[string]string
+}
+
+// NewResourceFilter creates a filter from an RSQL query, comma separated tags and comma separated key=value attributes.
+// All of the query, tags and attributes must match.
+func NewResourceFilter(query, tags, attributes string) (*ResourceFilter, error) {
+	f := &ResourceFilter{
+		Query:      strings.TrimSpace(query),
+		Tags:       []string{},
+		Attributes: map[string]string{},
+	}
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			f.Tags = append(f.Tags, tag)
+		}
+	}
+	for _, pair := range strings.Split(attributes, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		elements := strings.SplitN(pair, "=", 2)
+		if len(elements) != 2 || elements[0] == "" {
+			return nil, fmt.Errorf("invalid attribute filter %q, expected key=value", pair)
+		}
+		f.Attributes[elements[0]] = elements[1]
+	}
+	return f, nil
+}
+
+// IsEmpty returns true when the filter selects every resource
+func (f *ResourceFilter) IsEmpty() bool {
+	return f == nil || (f.Query == "" && len(f.Tags) == 0 && len(f.Attributes) == 0)
+}
+
+// String returns the RSQL query for the filter
+func (f *ResourceFilter) String() string {
+	if f.IsEmpty() {
+		return ""
+	}
+	conditions := []string{}
+	if f.Query != "" {
+		conditions = append(conditions, fmt.Sprintf("(%s)", f.Query))
+	}
+	for _, tag := range f.Tags {
+		conditions = append(conditions, fmt.Sprintf("tags==%q", tag))
+	}
+	keys := make([]string, 0, len(f.Attributes))
+	for key := range f.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conditions = append(conditions, fmt.Sprintf("attributes.%s==%q", key, f.Attributes[key]))
+	}
+	return strings.Join(conditions, ";")
+}
+
+// params returns the query params for the base query combined with the filter, nil when there is no query
+func (f *ResourceFilter) params(baseQuery string) map[string]string {
+	conditions := []string{}
+	if baseQuery != "" {
+		conditions = append(conditions, baseQuery)
+	}
+	if query := f.String(); query != "" {
+		conditions = append(conditions, query)
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	return map[string]string{
+		"query": strings.Join(conditions, ";"),
+	}
+}
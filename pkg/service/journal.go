@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// JournalOperation is the kind of change recorded in a journal entry
+type JournalOperation string
+
+const (
+	// JournalCreate a resource was created, it is the after state
+	JournalCreate JournalOperation = "create"
+	// JournalDelete a resource was deleted, it is the before state
+	JournalDelete JournalOperation = "delete"
+	// JournalUpdate a resource was updated, before and after are its states
+	JournalUpdate JournalOperation = "update"
+	// JournalState the state sub resource of a resource was changed
+	JournalState JournalOperation = "state"
+	// JournalLost a change that can not be reversed, e.g. the subscriptions of a deleted plan, the reason says what was lost
+	JournalLost JournalOperation = "lost"
+)
+
+// JournalEntry is a single mutating call, one json line in the journal
+type JournalEntry struct {
+	Time        time.Time            `json:"time"`
+	Operation   JournalOperation     `json:"operation"`
+	Kind        string               `json:"kind"`
+	Scope       string               `json:"scope,omitempty"`
+	Name        string               `json:"name"`
+	Before      *v1.ResourceInstance `json:"before,omitempty"`
+	After       *v1.ResourceInstance `json:"after,omitempty"`
+	StateBefore string               `json:"stateBefore,omitempty"`
+	StateAfter  string               `json:"stateAfter,omitempty"`
+	Reason      string               `json:"reason,omitempty"`
+	RolledBack  *time.Time           `json:"rolledBack,omitempty"`
+}
+
+// Journal records the mutating calls made to Amplify so that they may be rolled back, a nil journal records nothing
+type Journal struct {
+	logger *logrus.Logger
+	file   *os.File
+	lock   sync.Mutex
+}
+
+// NewJournal creates the journal file. An existing journal is only appended to when appendExisting is set, otherwise
+// a rollback of it would also reverse the changes of the earlier runs.
+func NewJournal(logger *logrus.Logger, fileName string, appendExisting bool) (*Journal, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_EXCL
+	if appendExisting {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(fileName, flags, 0644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("the journal %s already exists, use another journal or append to it", fileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{logger: logger, file: file}, nil
+}
+
+// Created records a resource that was created
+func (j *Journal) Created(after v1.Interface) {
+	if j == nil {
+		return
+	}
+	ri, _ := after.AsInstance()
+	j.record(JournalEntry{Operation: JournalCreate, After: ri}, ri)
+}
+
+// Deleted records a resource that was deleted
+func (j *Journal) Deleted(before v1.Interface) {
+	if j == nil {
+		return
+	}
+	ri, _ := before.AsInstance()
+	j.record(JournalEntry{Operation: JournalDelete, Before: ri}, ri)
+}
+
+// Updated records a resource that was updated, before is the resource as it was read
+func (j *Journal) Updated(before, after v1.Interface) {
+	if j == nil {
+		return
+	}
+	beforeRI, _ := before.AsInstance()
+	afterRI, _ := after.AsInstance()
+	j.record(JournalEntry{Operation: JournalUpdate, Before: beforeRI, After: afterRI}, beforeRI)
+}
+
+// Lost records what a change removed that can not be restored by a rollback
+func (j *Journal) Lost(resource v1.Interface, reason string) {
+	if j == nil {
+		return
+	}
+	ri, _ := resource.AsInstance()
+	j.record(JournalEntry{Operation: JournalLost, Before: ri, Reason: reason}, ri)
+}
+
+// StateChanged records a change to the state of a resource, the state before is not known when it is nil
+func (j *Journal) StateChanged(resource v1.Interface, before interface{}, after string) {
+	if j == nil {
+		return
+	}
+	ri, _ := resource.AsInstance()
+	entry := JournalEntry{Operation: JournalState, Before: ri, StateAfter: after}
+	if before != nil {
+		entry.StateBefore = fmt.Sprint(before)
+	}
+	j.record(entry, ri)
+}
+
+func (j *Journal) record(entry JournalEntry, ri *v1.ResourceInstance) {
+	entry.Time = time.Now().UTC()
+	if ri != nil {
+		entry.Kind = ri.Kind
+		entry.Scope = ri.Metadata.Scope.Name
+		entry.Name = ri.Name
+	}
+	logger := j.logger.
+		WithField("operation", entry.Operation).
+		WithField("kind", entry.Kind).
+		WithField("name", entry.Name)
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		logger.WithError(err).Error("unable to record change in journal")
+		return
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if _, err := j.file.Write(append(buf, '\n')); err != nil {
+		logger.WithError(err).Error("unable to record change in journal")
+	}
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// ReadJournal reads the entries of a journal, in the order the calls were made
+func ReadJournal(fileName string) ([]JournalEntry, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []JournalEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := JournalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// WriteJournal replaces the entries of a journal, e.g. to mark the entries that were rolled back
+func WriteJournal(fileName string, entries []JournalEntry) error {
+	buf := []byte{}
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	// write a new file first so that the journal is not lost when the write fails
+	tmpFile := fileName + ".tmp"
+	if err := os.WriteFile(tmpFile, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, fileName)
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+)
+
+// Manifest describes an export so that it can be verified before it is imported
+type Manifest struct {
+	ToolVersion  string            `json:"toolVersion"`
+	OrgID        string            `json:"orgId"`
+	Region       string            `json:"region"`
+	Environments []string          `json:"environments"`
+	Timestamp    time.Time         `json:"timestamp"`
+	File         string            `json:"file"`
+	Format       string            `json:"format"`
+	Changeset    bool              `json:"changeset,omitempty"`
+	FileHash     string            `json:"fileHash"`
+	Counts       map[string]int    `json:"counts"`
+	Resources    map[string]string `json:"resources,omitempty"`
+	SpecFiles    map[string]string `json:"specFiles,omitempty"`
+}
+
+// ManifestFileName returns the name of the manifest written next to an export file or directory
+func ManifestFileName(fileName string) string {
+	fileName = strings.TrimSuffix(fileName, string(filepath.Separator))
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".manifest.json"
+}
+
+// NewManifest creates the manifest for the resources written to the export file
+func NewManifest(fileName, format string, resources []v1.Interface) (*Manifest, error) {
+	fileHash, err := FileHash(fileName)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{
+		Timestamp: time.Now().UTC(),
+		File:      filepath.Base(fileName),
+		Format:    format,
+		FileHash:  fileHash,
+		Counts:    map[string]int{},
+		Resources: map[string]string{},
+		SpecFiles: map[string]string{},
+	}
+	for _, res := range resources {
+		ri, err := res.AsInstance()
+		if err != nil {
+			return nil, err
+		}
+		m.Counts[ri.Kind]++
+		if err := m.addResource(fileName, "", ri); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// addResource adds the hash of the resource, under the key prefix, and of the spec file it references to the manifest
+func (m *Manifest) addResource(fileName, prefix string, ri *v1.ResourceInstance) error {
+	hash, err := ResourceHash(ri)
+	if err != nil {
+		return err
+	}
+	m.Resources[prefix+ResourceKey(ri)] = hash
+
+	ref := specFileRef(ri)
+	if ref == "" {
+		return nil
+	}
+	specFile, err := specFilePath(filepath.Dir(fileName), ref)
+	if err != nil {
+		return fmt.Errorf("unable to hash the spec file of revision %s: %w", ri.Name, err)
+	}
+	specHash, err := FileHash(specFile)
+	if err != nil {
+		return fmt.Errorf("unable to hash the spec file of revision %s: %w", ri.Name, err)
+	}
+	m.SpecFiles[ref] = specHash
+	return nil
+}
+
+// specFileRef returns the spec file referenced by a revision, written by the export extract_specs option, if any
+func specFileRef(ri *v1.ResourceInstance) string {
+	if ri.Kind != management.APIServiceRevisionGVK().Kind {
+		return ""
+	}
+	rev := management.NewAPIServiceRevision("", ri.Metadata.Scope.Name)
+	rev.FromInstance(ri)
+	if !strings.HasPrefix(rev.Spec.Definition.Value, SpecFilePrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(rev.Spec.Definition.Value, SpecFilePrefix)
+}
+
+// NewChangesetManifest creates the manifest for a changeset written to the export file
+func NewChangesetManifest(fileName, format string, cs *Changeset) (*Manifest, error) {
+	fileHash, err := FileHash(fileName)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{
+		Timestamp: time.Now().UTC(),
+		File:      filepath.Base(fileName),
+		Format:    format,
+		Changeset: true,
+		FileHash:  fileHash,
+		Counts:    map[string]int{},
+		Resources: map[string]string{},
+		SpecFiles: map[string]string{},
+	}
+	for section, resources := range cs.sections() {
+		m.Counts[section] = len(resources)
+		for _, res := range resources {
+			ri, err := res.AsInstance()
+			if err != nil {
+				return nil, err
+			}
+			// deleted revisions are from the previous export, their spec files may no longer be there
+			if section == "delete" {
+				hash, err := ResourceHash(ri)
+				if err != nil {
+					return nil, err
+				}
+				m.Resources[section+"/"+ResourceKey(ri)] = hash
+				continue
+			}
+			if err := m.addResource(fileName, section+"/", ri); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// ReadManifest reads a manifest file
+func ReadManifest(fileName string) (*Manifest, error) {
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	err = json.Unmarshal(buf, m)
+	return m, err
+}
+
+// FileHash returns the SHA-256 of a file, or of the relative paths and content of every file in a directory
+func FileHash(fileName string) (string, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if !info.IsDir() {
+		buf, err := os.ReadFile(fileName)
+		if err != nil {
+			return "", err
+		}
+		h.Write(buf)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	// WalkDir visits the files in lexical order so the hash is stable
+	err = filepath.WalkDir(fileName, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(fileName, path)
+		if err != nil {
+			return err
+		}
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(buf)
+		fmt.Fprintf(h, "%s %s\n", filepath.ToSlash(rel), hex.EncodeToString(sum[:]))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyManifest checks the export file and the spec files it references against its manifest and returns a
+// description of each problem found
+func VerifyManifest(m *Manifest, fileName string) ([]string, error) {
+	problems := verifySpecFiles(m, fileName)
+
+	fileHash, err := FileHash(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if fileHash == m.FileHash {
+		return problems, nil
+	}
+	problems = append(problems, fmt.Sprintf("file hash %s does not match the manifest hash %s", fileHash, m.FileHash))
+
+	// find the resources that differ from the manifest, a changeset keys them by section
+	sections := map[string][]*v1.ResourceInstance{}
+	if m.Changeset {
+		sections, err = ReadChangeset(fileName, m.Format)
+	} else {
+		sections[""], err = ReadResources(fileName)
+	}
+	if err != nil {
+		return append(problems, fmt.Sprintf("unable to read the resources: %s", err)), nil
+	}
+	found := map[string]struct{}{}
+	for section, resources := range sections {
+		prefix := ""
+		if m.Changeset {
+			prefix = section + "/"
+		}
+		for _, ri := range resources {
+			key := prefix + ResourceKey(ri)
+			found[key] = struct{}{}
+			expected, ok := m.Resources[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s is not in the manifest", key))
+				continue
+			}
+			hash, err := ResourceHash(ri)
+			if err != nil {
+				return nil, err
+			}
+			if hash != expected {
+				problems = append(problems, fmt.Sprintf("%s has been modified", key))
+			}
+		}
+	}
+
+	missing := []string{}
+	for key := range m.Resources {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, fmt.Sprintf("%s is missing", key))
+		}
+	}
+	sort.Strings(missing)
+	return append(problems, missing...), nil
+}
+
+// verifySpecFiles checks the spec files, relative to the export file, against their hashes in the manifest
+func verifySpecFiles(m *Manifest, fileName string) []string {
+	refs := make([]string, 0, len(m.SpecFiles))
+	for ref := range m.SpecFiles {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	problems := []string{}
+	for _, ref := range refs {
+		specFile, err := specFilePath(filepath.Dir(fileName), ref)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		hash, err := FileHash(specFile)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("spec file %s can not be read: %s", ref, err))
+			continue
+		}
+		if hash != m.SpecFiles[ref] {
+			problems = append(problems, fmt.Sprintf("spec file %s has been modified", ref))
+		}
+	}
+	return problems
+}
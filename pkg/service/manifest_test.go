@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+)
+
+// writeTestExport saves a service and a revision, with its spec extracted to a file, as a yaml export with a manifest
+func writeTestExport(t *testing.T, services ...string) (string, *Manifest) {
+	t.Helper()
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "export.yaml")
+
+	resources := []v1.Interface{}
+	for _, name := range services {
+		resources = append(resources, testService(t, "env", name, name))
+	}
+	rev := management.NewAPIServiceRevision("petstore-rev", "env")
+	rev.Spec.ApiService = "petstore"
+	rev.Spec.Definition.Type = "oas3"
+	rev.Spec.Definition.Value = base64.StdEncoding.EncodeToString([]byte("openapi: 3.0.0"))
+	if err := extractSpecFile(dir, "specs", rev); err != nil {
+		t.Fatal(err)
+	}
+	resources = append(resources, rev)
+
+	if err := SaveResources(nil, fileName, FormatYAML, resources); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManifest(fileName, FormatYAML, resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileName, m
+}
+
+func resaveTestExport(t *testing.T, fileName string, resources []*v1.ResourceInstance) {
+	t.Helper()
+	res := []v1.Interface{}
+	for _, ri := range resources {
+		res = append(res, ri)
+	}
+	if err := SaveResources(nil, fileName, FormatYAML, res); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	petstoreKey := ResourceKey(testService(t, "env", "petstore", "petstore"))
+	ordersKey := ResourceKey(testService(t, "env", "orders", "orders"))
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, fileName string)
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(t *testing.T, fileName string) {},
+		},
+		{
+			name: "resource modified",
+			modify: func(t *testing.T, fileName string) {
+				resources, err := ReadResources(fileName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resources[0].Title = "changed"
+				resaveTestExport(t, fileName, resources)
+			},
+			want: []string{"file hash", petstoreKey + " has been modified"},
+		},
+		{
+			name: "resource added",
+			modify: func(t *testing.T, fileName string) {
+				resources, err := ReadResources(fileName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resaveTestExport(t, fileName, append(resources, testService(t, "env", "orders", "orders")))
+			},
+			want: []string{"file hash", ordersKey + " is not in the manifest"},
+		},
+		{
+			name: "resource removed",
+			modify: func(t *testing.T, fileName string) {
+				resources, err := ReadResources(fileName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resaveTestExport(t, fileName, resources[1:])
+			},
+			want: []string{"file hash", petstoreKey + " is missing"},
+		},
+		{
+			name: "spec file modified",
+			modify: func(t *testing.T, fileName string) {
+				specFile := filepath.Join(filepath.Dir(fileName), "specs", "env", "petstore", "petstore-rev.yaml")
+				if err := os.WriteFile(specFile, []byte("openapi: 3.1.0"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"spec file specs/env/petstore/petstore-rev.yaml has been modified"},
+		},
+		{
+			name: "spec file removed",
+			modify: func(t *testing.T, fileName string) {
+				if err := os.RemoveAll(filepath.Join(filepath.Dir(fileName), "specs")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"spec file specs/env/petstore/petstore-rev.yaml can not be read"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fileName, m := writeTestExport(t, "petstore")
+			tc.modify(t, fileName)
+			problems, err := VerifyManifest(m, fileName)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(problems) != len(tc.want) {
+				t.Fatalf("got problems %q, want %q", problems, tc.want)
+			}
+			for i, want := range tc.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d is %q, want it to contain %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatDir  = "dir"
+)
+
+// SaveResources writes the resources as a json array, yaml documents or a directory per kind and scope
+func SaveResources(logger *logrus.Logger, fileName, format string, resources []v1.Interface) error {
+	switch format {
+	case "", FormatJSON:
+		SaveToFile(logger, "export", fileName, resources)
+		return nil
+	case FormatYAML:
+		buf, err := resourcesToYAML(resources)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(fileName, buf, 0777)
+	case FormatDir:
+		if err := checkEmptyDir(fileName); err != nil {
+			return err
+		}
+		return saveResourcesToDir(fileName, resources)
+	}
+	return fmt.Errorf("unknown output format %s, expected one of %s, %s or %s", format, FormatJSON, FormatYAML, FormatDir)
+}
+
+func resourcesToYAML(resources []v1.Interface) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	for _, res := range resources {
+		obj, err := toGeneric(res)
+		if err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(obj); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkEmptyDir returns an error when the directory exists and is not empty, the files of an earlier export left in it
+// would otherwise be read, verified and imported with the new ones
+func checkEmptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("output directory %s is not empty, remove it or choose another out_file", dir)
+	}
+	return nil
+}
+
+// saveResourcesToDir writes each resource to <dir>/<kind>/<scope kind>/<scope>/<name>.yaml, or to <dir>/<kind>/<name>.yaml
+// when it is not scoped. The scope kind keeps apart resources with the same name in scopes of different kinds.
+func saveResourcesToDir(dir string, resources []v1.Interface) error {
+	for _, res := range resources {
+		ri, err := res.AsInstance()
+		if err != nil {
+			return err
+		}
+		buf, err := resourcesToYAML([]v1.Interface{ri})
+		if err != nil {
+			return err
+		}
+		fileName := filepath.Join(dir, ri.Kind, ri.Metadata.Scope.Kind, ri.Metadata.Scope.Name, ri.Name+".yaml")
+		if err := os.MkdirAll(filepath.Dir(fileName), 0777); err != nil {
+			return err
+		}
+		if err := os.WriteFile(fileName, buf, 0777); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toGeneric converts an object to the generic form of its json so the yaml output uses the json field names. Whole
+// numbers are kept as integers, as a float64 is written to yaml in exponent form, e.g. 1e+06, which is not an integer.
+func toGeneric(res interface{}) (interface{}, error) {
+	buf, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	var obj interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return convertNumbers(obj), nil
+}
+
+// convertNumbers replaces the json numbers in a generic object with an int64 for whole numbers and a float64 otherwise
+func convertNumbers(obj interface{}) interface{} {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return obj
+}
+
+// ReadResources reads the resources from a json file, a yaml file or a directory of yaml files
+func ReadResources(fileName string) ([]*v1.ResourceInstance, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readResourcesFile(fileName)
+	}
+
+	resources := []*v1.ResourceInstance{}
+	err = filepath.WalkDir(fileName, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isYAMLFile(path) {
+			return nil
+		}
+		fileResources, err := readResourcesFile(path)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, fileResources...)
+		return nil
+	})
+	return resources, err
+}
+
+func isYAMLFile(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func readResourcesFile(fileName string) ([]*v1.ResourceInstance, error) {
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []*v1.ResourceInstance{}
+	if !isYAMLFile(fileName) {
+		err = json.Unmarshal(buf, &resources)
+		return resources, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(buf))
+	for {
+		var obj interface{}
+		err := decoder.Decode(&obj)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", fileName, err)
+		}
+		if obj == nil {
+			continue
+		}
+		jsonBuf, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", fileName, err)
+		}
+		ri := &v1.ResourceInstance{}
+		if err := json.Unmarshal(jsonBuf, ri); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", fileName, err)
+		}
+		resources = append(resources, ri)
+	}
+	return resources, nil
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Axway/agent-sdk/pkg/apic"
@@ -13,12 +15,16 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+type productCatalogOpt func(p *productCatalog)
+
 type ProductCatalog interface {
-	ReadProducts()
+	ReadProducts() error
 	WriteProducts()
+	GetProductOutput() []v1.Interface
 	PreProcessProductForAssetRepair()
 	PostProcessProductForAssetRepair()
 	RepairProductWithBackup()
+	QuotasForAssetResource(assetName, assetResourceName string) []string
 }
 
 type productCatalog struct {
@@ -28,11 +34,17 @@ type productCatalog struct {
 	ProductsBackup map[string]ProductInfo
 	backupFile     string
 	assetCatalog   AssetCatalog
+	repairSelector *RepairSelector
+	journal        *Journal
+	readDocuments  bool
+	stripData      bool
+	keepOwners     bool
+	readFailures   int
 	dryRun         bool
 }
 
-func NewProductCatalog(logger *logrus.Logger, assetCatalog AssetCatalog, apicClient apic.Client, backupFile string, dryRun bool) ProductCatalog {
-	return &productCatalog{
+func NewProductCatalog(logger *logrus.Logger, assetCatalog AssetCatalog, apicClient apic.Client, backupFile string, dryRun bool, opts ...productCatalogOpt) ProductCatalog {
+	p := &productCatalog{
 		logger:         logger,
 		apicClient:     apicClient,
 		Products:       make(map[string]ProductInfo),
@@ -41,19 +53,135 @@ func NewProductCatalog(logger *logrus.Logger, assetCatalog AssetCatalog, apicCli
 		assetCatalog:   assetCatalog,
 		dryRun:         dryRun,
 	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+func WithProductDocuments() productCatalogOpt {
+	return func(p *productCatalog) {
+		p.readDocuments = true
+	}
+}
+
+func StripProductData() productCatalogOpt {
+	return func(p *productCatalog) {
+		p.stripData = true
+	}
+}
+
+func WithProductOwners(keepOwners bool) productCatalogOpt {
+	return func(p *productCatalog) {
+		p.keepOwners = keepOwners
+	}
+}
+
+// WithProductRepairSelector only prepares the products in error with an asset selected by the selector for asset repair
+func WithProductRepairSelector(selector *RepairSelector) productCatalogOpt {
+	return func(p *productCatalog) {
+		p.repairSelector = selector
+	}
+}
+
+// WithProductJournal records the changes made to the products for the asset repair in the journal
+func WithProductJournal(journal *Journal) productCatalogOpt {
+	return func(p *productCatalog) {
+		p.journal = journal
+	}
 }
 
 func (t *productCatalog) WriteProducts() {
 	SaveToFile(t.logger, "product-catalog", "product-catalog.json", t.Products)
 }
 
-func (t *productCatalog) ReadProducts() {
+func (t *productCatalog) GetProductOutput() []v1.Interface {
+	objs := []v1.Interface{}
+	for _, productInfo := range t.Products {
+		if !t.productAssetsFound(productInfo.Product) {
+			t.logger.
+				WithField("productName", productInfo.Product.Name).
+				Debug("skipping product, not all of its assets are in the output")
+			continue
+		}
+		objs = append(objs, productInfo.Product)
+		// the product release is created by the server from its release tag, so only the tag is output
+		for _, productRelease := range productInfo.ProductReleases {
+			if productRelease.ReleaseTag != nil {
+				objs = append(objs, productRelease.ReleaseTag)
+			}
+			objs = appendPlanOutput(objs, productRelease.Plans)
+		}
+		objs = appendPlanOutput(objs, productInfo.PlansWithNoRelease)
+		for _, document := range productInfo.Documents {
+			objs = append(objs, document)
+		}
+	}
+
+	// clean data
+	if t.stripData {
+		cleanObjs := []v1.Interface{}
+		wg := sync.WaitGroup{}
+		wg.Add(len(objs))
+		for _, obj := range objs {
+			func(i v1.Interface) {
+				defer wg.Done()
+				ri, _ := i.AsInstance()
+
+				// clean sub resources by kind
+				switch ri.Kind {
+				case catalog.ProductGVK().Kind:
+					p := catalog.NewProduct("")
+					p.FromInstance(ri)
+					p.Icon = struct{}{}
+					ri, _ = p.AsInstance()
+				}
+
+				stripResource(ri, t.keepOwners)
+				cleanObjs = append(cleanObjs, ri)
+			}(obj)
+		}
+		wg.Wait()
+		objs = cleanObjs
+	}
+	return objs
+}
+
+func appendPlanOutput(objs []v1.Interface, plans map[string]PlanInfo) []v1.Interface {
+	for _, plan := range plans {
+		objs = append(objs, plan.Plan)
+		for _, quota := range plan.Quotas {
+			objs = append(objs, quota.Quota)
+		}
+	}
+	return objs
+}
+
+// productAssetsFound returns true when every asset in the product is known to the asset catalog
+func (t *productCatalog) productAssetsFound(product *catalog.Product) bool {
+	if len(product.Spec.Assets) == 0 {
+		return false
+	}
+	for _, asset := range product.Spec.Assets {
+		if t.assetCatalog.FindAsset(asset.Name) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadProducts reads the products with their releases, plans and quotas. An error is returned when any of them could
+// not be read, as the products are then incomplete.
+func (t *productCatalog) ReadProducts() error {
 	t.logger.Info("Reading Products...")
+	t.readFailures = 0
 	p := catalog.NewProduct("")
 	products, err := t.apicClient.GetAPIV1ResourceInstances(nil, p.GetKindLink())
 	if err != nil {
 		t.logger.WithError(err).Error("unable to read products")
-		return
+		return err
 	}
 
 	for _, product := range products {
@@ -73,9 +201,46 @@ func (t *productCatalog) ReadProducts() {
 			ProductReleases:    productReleases,
 			PlansWithNoRelease: plansWithNoRelease,
 		}
+		if t.readDocuments {
+			productInfo.Documents = t.readProductDocuments(logger, cp.Name)
+		}
 
 		t.Products[product.GetMetadata().ID] = productInfo
 	}
+	if t.readFailures > 0 {
+		return fmt.Errorf("%d product releases, plans or quotas could not be read", t.readFailures)
+	}
+	return nil
+}
+
+func (t *productCatalog) readProductDocuments(logger *logrus.Entry, productName string) []*v1.ResourceInstance {
+	documents := []*v1.ResourceInstance{}
+	for _, gvk := range []v1.GroupVersionKind{catalog.ResourceGVK(), catalog.DocumentGVK()} {
+		ri := &v1.ResourceInstance{
+			ResourceMeta: v1.ResourceMeta{
+				GroupVersionKind: gvk,
+				Metadata: v1.Metadata{
+					Scope: v1.MetadataScope{
+						Kind: catalog.ProductGVK().Kind,
+						Name: productName,
+					},
+				},
+			},
+		}
+		productDocuments, err := t.apicClient.GetAPIV1ResourceInstances(nil, ri.GetKindLink())
+		if err != nil {
+			logger.WithError(err).WithField("kind", gvk.Kind).Error("unable to read product documents")
+			continue
+		}
+		for _, document := range productDocuments {
+			logger.
+				WithField("kind", document.Kind).
+				WithField("productDocument", document.Name).
+				Debug("Reading product document ok")
+		}
+		documents = append(documents, productDocuments...)
+	}
+	return documents
 }
 
 func (t *productCatalog) readProductReleases(logger *logrus.Entry, productID, productName string) map[string]ProductReleaseInfo {
@@ -87,6 +252,7 @@ func (t *productCatalog) readProductReleases(logger *logrus.Entry, productID, pr
 	productReleases, err := t.apicClient.GetAPIV1ResourceInstances(params, p.GetKindLink())
 	if err != nil {
 		logger.WithError(err).Error("unable to read product releases")
+		t.readFailures++
 		return productReleaseInfos
 	}
 	for _, productRelease := range productReleases {
@@ -155,6 +321,7 @@ func (t *productCatalog) readProductPlans(logger *logrus.Entry, referenceID stri
 	productPlans, err := t.apicClient.GetAPIV1ResourceInstances(params, p.GetKindLink())
 	if err != nil {
 		logger.WithError(err).Error("unable to read product plans")
+		t.readFailures++
 		return plans
 	}
 
@@ -187,6 +354,7 @@ func (t *productCatalog) readPlanQuotas(logger *logrus.Entry, planName string) m
 	planQuotas, err := t.apicClient.GetAPIV1ResourceInstances(nil, q.GetKindLink())
 	if err != nil {
 		logger.WithError(err).Error("unable to read quotas")
+		t.readFailures++
 		return quotaInfos
 	}
 
@@ -206,6 +374,36 @@ func (t *productCatalog) readPlanQuotas(logger *logrus.Entry, planName string) m
 	return quotaInfos
 }
 
+// QuotasForAssetResource returns the quotas, as product/plan/quota, of the read products that reference the asset resource
+func (t *productCatalog) QuotasForAssetResource(assetName, assetResourceName string) []string {
+	quotas := []string{}
+	for _, productInfo := range t.Products {
+		plans := []PlanInfo{}
+		for _, productRelease := range productInfo.ProductReleases {
+			for _, plan := range productRelease.Plans {
+				plans = append(plans, plan)
+			}
+		}
+		for _, plan := range productInfo.PlansWithNoRelease {
+			plans = append(plans, plan)
+		}
+
+		logger := t.logger.WithField("productName", productInfo.Product.Name)
+		for _, plan := range plans {
+			for _, quota := range plan.Quotas {
+				for _, ar := range t.readQuotaAssetResources(logger, quota.Quota) {
+					if ar.Name == assetResourceName && ar.Metadata.Scope.Name == assetName {
+						quotas = append(quotas, fmt.Sprintf("%s/%s/%s", productInfo.Product.Name, plan.Plan.Name, quota.Quota.Name))
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(quotas)
+	return quotas
+}
+
+// readQuotaAssetResources returns the asset resources referenced by the quota, by asset/resource name
 func (t *productCatalog) readQuotaAssetResources(logger *logrus.Entry, quota *catalog.Quota) map[string]*catalog.AssetResource {
 	quotaAssetResources := make(map[string]*catalog.AssetResource)
 	for _, resource := range quota.Spec.Resources {
@@ -213,8 +411,12 @@ func (t *productCatalog) readQuotaAssetResources(logger *logrus.Entry, quota *ca
 		qar := &catalog.QuotaSpecAssetResourceRef{}
 		json.Unmarshal(buf, qar)
 		nameElements := strings.Split(qar.Name, "/")
+		if len(nameElements) != 2 {
+			continue
+		}
 		ar, _ := catalog.NewAssetResource(nameElements[1], catalog.AssetGVK().Kind, nameElements[0])
-		quotaAssetResources[ar.Name] = ar
+		// asset resources are only unique within their asset
+		quotaAssetResources[qar.Name] = ar
 		logger.
 			WithField("quotaAssetResource", ar.Name).
 			WithField("quotaAssetResourceScope", ar.Metadata.Scope.Name).
@@ -226,7 +428,7 @@ func (t *productCatalog) readQuotaAssetResources(logger *logrus.Entry, quota *ca
 
 func (t *productCatalog) PreProcessProductForAssetRepair() {
 	for _, product := range t.Products {
-		if product.Product.Status != nil && product.Product.Status.Level == "Error" {
+		if product.Product.Status != nil && product.Product.Status.Level == "Error" && t.repairsProduct(product) {
 			logger := t.logger.
 				WithField("productID", product.Product.Metadata.ID).
 				WithField("productName", product.Product.Name)
@@ -248,9 +450,22 @@ func (t *productCatalog) PreProcessProductForAssetRepair() {
 	}
 }
 
+// repairsProduct returns true when every asset is selected for repair or the product has an asset that is selected
+func (t *productCatalog) repairsProduct(product ProductInfo) bool {
+	if t.repairSelector.IsEmpty() {
+		return true
+	}
+	for _, asset := range product.Product.Spec.Assets {
+		if t.repairSelector.Selects(t.assetCatalog.FindAsset(asset.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *productCatalog) PostProcessProductForAssetRepair() {
 	for _, product := range t.Products {
-		if product.Product.Status != nil && product.Product.Status.Level == "Error" {
+		if product.Product.Status != nil && product.Product.Status.Level == "Error" && t.repairsProduct(product) {
 			logger := t.logger.
 				WithField("productID", product.Product.Metadata.ID).
 				WithField("productName", product.Product.Name)
@@ -316,6 +531,7 @@ func (t *productCatalog) removeProductPlan(logger *logrus.Entry, plan PlanInfo)
 				logger.WithError(statusErr).Error("error deprecating plan")
 				break
 			}
+			t.journal.StateChanged(plan.Plan, plan.Plan.State, string(catalog.ProductPlanStateDEPRECATED))
 		}
 		fallthrough
 	case catalog.ProductPlanStateDEPRECATED:
@@ -327,6 +543,7 @@ func (t *productCatalog) removeProductPlan(logger *logrus.Entry, plan PlanInfo)
 				logger.WithError(statusErr).Error("error deprecating plan")
 				break
 			}
+			t.journal.StateChanged(plan.Plan, catalog.ProductPlanStateDEPRECATED, string(catalog.ProductPlanStateARCHIVED))
 		}
 		fallthrough
 	case catalog.ProductPlanStateARCHIVED:
@@ -340,6 +557,12 @@ func (t *productCatalog) removeProductPlan(logger *logrus.Entry, plan PlanInfo)
 				logger.WithError(statusErr).Error("error deleting plan")
 				break
 			}
+			// the quotas are deleted with the plan, the plan is recorded last so that a rollback creates it first
+			for _, quota := range plan.Quotas {
+				t.journal.Deleted(quota.Quota)
+			}
+			t.journal.Lost(plan.Plan, "the subscriptions to the plan were removed with it and are not restored")
+			t.journal.Deleted(plan.Plan)
 		}
 	}
 }
@@ -361,6 +584,7 @@ func (t *productCatalog) archiveCurrentProductRelease(logger *logrus.Entry, prod
 					logger.WithError(statusErr).Error("error deprecating plan")
 					break
 				}
+				t.journal.StateChanged(releaseTag, releaseTag.State, string(catalog.ProductStateARCHIVED))
 			}
 		}
 	}
@@ -383,6 +607,7 @@ func (t *productCatalog) deprecateCurrentProductRelease(logger *logrus.Entry, pr
 					logger.WithError(statusErr).Error("error deprecating plan")
 					break
 				}
+				t.journal.StateChanged(releaseTag, releaseTag.State, string(catalog.ProductStateDEPRECATED))
 			}
 			releaseTag.State = string(catalog.ProductStateDEPRECATED)
 		}
@@ -397,6 +622,7 @@ func (t *productCatalog) setProductStateToDraft(logger *logrus.Entry, product Pr
 			logger.WithError(statusErr).Errorf("unable to transition asset %s to draft", product.Product.Name)
 			return statusErr
 		}
+		t.journal.StateChanged(product.Product, product.Product.State, string(catalog.ProductStateDRAFT))
 	}
 	return nil
 }
@@ -412,9 +638,11 @@ func (t *productCatalog) setProductReleaseTypeToManual(logger *logrus.Entry, pro
 	p.Spec = product.Product.Spec
 	p.Spec.AutoRelease = nil
 	if !t.dryRun {
-		_, err := t.apicClient.UpdateResourceInstance(p)
+		updated, err := t.apicClient.UpdateResourceInstance(p)
 		if err != nil {
 			logger.WithError(err).Errorf("unable to update asset %s for draft", product.Product.Name)
+		} else {
+			t.journal.Updated(product.Product, updated)
 		}
 	}
 }
@@ -434,9 +662,11 @@ func (t *productCatalog) reapplyAutoRelease(logger *logrus.Entry, product Produc
 		}
 	}
 	if !t.dryRun {
-		_, err := t.apicClient.UpdateResourceInstance(p)
+		updated, err := t.apicClient.UpdateResourceInstance(p)
 		if err != nil {
 			logger.WithError(err).Error("unable to update product auto release")
+		} else {
+			t.journal.Updated(ri, updated)
 		}
 	}
 }
@@ -456,6 +686,7 @@ func (t *productCatalog) createReleaseTag(logger *logrus.Entry, product ProductI
 		logger.WithError(err).Errorf("unable to create new release tag for product:%s", product.Product.Name)
 		return nil, err
 	}
+	t.journal.Created(releaseTagRI)
 	return releaseTagRI, err
 }
 
@@ -510,6 +741,7 @@ func (t *productCatalog) recreatePlan(logger *logrus.Entry, product ProductInfo,
 		logger.WithError(err).Error("unable to recreate product plan")
 		return nil, err
 	}
+	t.journal.Created(newPlanRI)
 	return newPlanRI, nil
 }
 
@@ -540,6 +772,7 @@ func (t *productCatalog) recreateQuota(logger *logrus.Entry, existingPlanInfo Pl
 					Errorf("unable to recreate quota")
 				quotaCreateError = true
 			} else {
+				t.journal.Created(newQuotaRI)
 				t.logger.Infof("Recreated quota id:%s, name: %s, plan: %s",
 					newQuotaRI.Metadata.ID,
 					newQuotaRI.Name,
@@ -581,6 +814,8 @@ func (t *productCatalog) ActivateProductPlan(plan v1.Interface) {
 		statusErr := t.apicClient.CreateSubResource(planRI.ResourceMeta, map[string]interface{}{"state": catalog.ProductPlanStateACTIVE})
 		if statusErr != nil {
 			t.logger.WithError(statusErr).Error("error activating plan")
+		} else {
+			t.journal.StateChanged(planRI, catalog.ProductPlanStateDRAFT, string(catalog.ProductPlanStateACTIVE))
 		}
 	}
 }
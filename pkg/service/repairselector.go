@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+)
+
+// RepairSelector selects the assets in error to repair, by name or ID and by the messages of their error status
+type RepairSelector struct {
+	Assets        map[string]struct{}
+	ExcludeAssets map[string]struct{}
+	ErrorMatch    *regexp.Regexp
+}
+
+// NewRepairSelector creates a selector from comma separated asset names or IDs to repair, comma separated asset
+// names or IDs to leave alone and a regular expression that a status message of the asset must match
+func NewRepairSelector(assets, excludeAssets, errorMatch string) (*RepairSelector, error) {
+	s := &RepairSelector{
+		Assets:        splitNames(assets),
+		ExcludeAssets: splitNames(excludeAssets),
+	}
+	if errorMatch != "" {
+		re, err := regexp.Compile(errorMatch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error match %q: %s", errorMatch, err)
+		}
+		s.ErrorMatch = re
+	}
+	return s, nil
+}
+
+func splitNames(names string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			set[name] = struct{}{}
+		}
+	}
+	return set
+}
+
+// IsEmpty returns true when the selector selects every asset in error
+func (s *RepairSelector) IsEmpty() bool {
+	return s == nil || (len(s.Assets) == 0 && len(s.ExcludeAssets) == 0 && s.ErrorMatch == nil)
+}
+
+// Selects returns true when the asset is in error and is selected for repair
+func (s *RepairSelector) Selects(asset *catalog.Asset) bool {
+	if asset == nil || asset.Status == nil || asset.Status.Level != "Error" {
+		return false
+	}
+	if s.IsEmpty() {
+		return true
+	}
+	if s.hasAsset(s.ExcludeAssets, asset) {
+		return false
+	}
+	if len(s.Assets) > 0 && !s.hasAsset(s.Assets, asset) {
+		return false
+	}
+	if s.ErrorMatch == nil {
+		return true
+	}
+	for _, reason := range asset.Status.Reasons {
+		if s.ErrorMatch.MatchString(reason.Detail) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RepairSelector) hasAsset(set map[string]struct{}, asset *catalog.Asset) bool {
+	if _, found := set[asset.Name]; found {
+		return true
+	}
+	_, found := set[asset.Metadata.ID]
+	return found
+}
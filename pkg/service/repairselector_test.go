@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+)
+
+func testAsset(name, id, level string, details ...string) *catalog.Asset {
+	asset := catalog.NewAsset(name)
+	asset.Metadata.ID = id
+	if level == "" {
+		return asset
+	}
+	asset.Status = &v1.ResourceStatus{Level: level}
+	for _, detail := range details {
+		asset.Status.Reasons = append(asset.Status.Reasons, v1.ResourceStatusReason{Type: level, Detail: detail})
+	}
+	return asset
+}
+
+func TestRepairSelectorSelects(t *testing.T) {
+	tests := []struct {
+		name          string
+		assets        string
+		excludeAssets string
+		errorMatch    string
+		asset         *catalog.Asset
+		want          bool
+	}{
+		{name: "asset in error", asset: testAsset("petstore", "1", "Error", "resource not found"), want: true},
+		{name: "asset without status", asset: testAsset("petstore", "1", ""), want: false},
+		{name: "asset not in error", asset: testAsset("petstore", "1", "Success"), want: false},
+		{name: "nil asset", asset: nil, want: false},
+		{name: "selected by name", assets: "orders, petstore", asset: testAsset("petstore", "1", "Error"), want: true},
+		{name: "selected by ID", assets: "1", asset: testAsset("petstore", "1", "Error"), want: true},
+		{name: "not selected", assets: "orders", asset: testAsset("petstore", "1", "Error"), want: false},
+		{name: "selected asset not in error", assets: "petstore", asset: testAsset("petstore", "1", "Success"), want: false},
+		{name: "excluded by name", excludeAssets: "petstore", asset: testAsset("petstore", "1", "Error"), want: false},
+		{name: "excluded by ID", excludeAssets: "1", asset: testAsset("petstore", "1", "Error"), want: false},
+		{name: "exclude wins over select", assets: "petstore", excludeAssets: "petstore", asset: testAsset("petstore", "1", "Error"), want: false},
+		{name: "other asset excluded", excludeAssets: "orders", asset: testAsset("petstore", "1", "Error"), want: true},
+		{name: "error matches", errorMatch: "resource .* not found", asset: testAsset("petstore", "1", "Error", "other", "resource x not found"), want: true},
+		{name: "error does not match", errorMatch: "resource .* not found", asset: testAsset("petstore", "1", "Error", "quota exceeded"), want: false},
+		{name: "error match without reasons", errorMatch: "not found", asset: testAsset("petstore", "1", "Error"), want: false},
+		{name: "selected and error matches", assets: "petstore", errorMatch: "not found", asset: testAsset("petstore", "1", "Error", "not found"), want: true},
+		{name: "selected and error does not match", assets: "petstore", errorMatch: "not found", asset: testAsset("petstore", "1", "Error", "quota exceeded"), want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewRepairSelector(tc.assets, tc.excludeAssets, tc.errorMatch)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := s.Selects(tc.asset); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewRepairSelectorInvalidErrorMatch(t *testing.T) {
+	if _, err := NewRepairSelector("", "", "resource ("); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
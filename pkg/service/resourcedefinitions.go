@@ -32,6 +32,7 @@ type ProductInfo struct {
 	Product            *catalog.Product              `json:"product,omitempty"`
 	ProductReleases    map[string]ProductReleaseInfo `json:"productReleases,omitempty"`
 	PlansWithNoRelease map[string]PlanInfo           `json:"planWithNoRelease,omitempty"`
+	Documents          []*v1.ResourceInstance        `json:"documents,omitempty"`
 }
 
 type ProductReleaseInfo struct {
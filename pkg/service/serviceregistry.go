@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"strings"
 	"sync"
 
@@ -38,9 +39,13 @@ type serviceRegistry struct {
 	envNames        map[string]string
 	mappingFile     string
 	outputFile      string
+	specsBaseDir    string
+	specsDir        string
+	filter          *ResourceFilter
 	getAllRevisions bool
 	getInstances    bool
 	stripData       bool
+	keepOwners      bool
 	dryRun          bool
 }
 
@@ -88,9 +93,10 @@ func WithOutputFile(outputFile string) serviceRegistryOpt {
 	}
 }
 
-func WithGetAllRevisions() serviceRegistryOpt {
+// WithGetAllRevisions reads every revision of the services, rather than only the revisions used by their instances
+func WithGetAllRevisions(getAllRevisions bool) serviceRegistryOpt {
 	return func(s *serviceRegistry) {
-		s.getAllRevisions = true
+		s.getAllRevisions = getAllRevisions
 	}
 }
 
@@ -100,6 +106,29 @@ func WithIncludeData(includeData bool) serviceRegistryOpt {
 	}
 }
 
+// WithSpecFiles writes the revision definitions to files in specsDir, relative to baseDir, rather than stripping them.
+// An empty specsDir leaves the definitions to be stripped.
+func WithSpecFiles(baseDir, specsDir string) serviceRegistryOpt {
+	return func(s *serviceRegistry) {
+		s.specsBaseDir = baseDir
+		s.specsDir = specsDir
+	}
+}
+
+// WithKeepOwners keeps the owner of the resources when the data is stripped
+func WithKeepOwners(keepOwners bool) serviceRegistryOpt {
+	return func(s *serviceRegistry) {
+		s.keepOwners = keepOwners
+	}
+}
+
+// WithServiceFilter only reads the APIServices matching the filter, and their revisions and instances
+func WithServiceFilter(filter *ResourceFilter) serviceRegistryOpt {
+	return func(s *serviceRegistry) {
+		s.filter = filter
+	}
+}
+
 func WithGetInstances() serviceRegistryOpt {
 	return func(s *serviceRegistry) {
 		s.getInstances = true
@@ -155,19 +184,20 @@ func (t *serviceRegistry) GetServicesOutput() []v1.Interface {
 					apisi.Spec.AccessRequestDefinition = ""
 					apisi.Spec.CredentialRequestDefinitions = []string{}
 					ri, _ = apisi.AsInstance()
+				case management.APIServiceRevisionGVK().Kind:
+					if t.specsDir == "" {
+						break
+					}
+					rev := management.NewAPIServiceRevision("", "")
+					rev.FromInstance(ri)
+					if err := extractSpecFile(t.specsBaseDir, t.specsDir, rev); err != nil {
+						t.logger.WithError(err).WithField("apiServiceRevision", rev.Name).Warn("leaving the definition inline")
+						break
+					}
+					ri, _ = rev.AsInstance()
 				}
 
-				ri.Metadata.Audit = v1.AuditMetadata{}
-				ri.Metadata.References = []v1.Reference{}
-				ri.Metadata.ID = ""
-				ri.Metadata.ResourceVersion = ""
-				ri.Metadata.Scope.ID = ""
-				ri.Metadata.Scope.SelfLink = ""
-				ri.Metadata.SelfLink = ""
-				ri.Owner = nil
-				ri.Tags = []string{}
-				ri.Attributes = map[string]string{}
-				ri.Finalizers = make([]v1.Finalizer, 0)
+				stripResource(ri, t.keepOwners)
 				cleanObjs = append(cleanObjs, ri)
 			}(obj)
 		}
@@ -214,8 +244,7 @@ func (t *serviceRegistry) ReadServices() {
 	wg := sync.WaitGroup{}
 	for _, env := range envs {
 		envName := env.GetName()
-		_, found := t.envNames[envName]
-		if len(t.envNames) > 0 && found {
+		if matchEnvironment(t.envNames, envName) {
 			t.envs = append(t.envs, envName)
 			wg.Add(1)
 			go func(envName string) {
@@ -228,9 +257,44 @@ func (t *serviceRegistry) ReadServices() {
 	wg.Wait()
 }
 
+// matchEnvironment returns true when the environment name matches one of the names or glob patterns
+func matchEnvironment(envNames map[string]string, envName string) bool {
+	if _, found := envNames[envName]; found {
+		return true
+	}
+	for pattern := range envNames {
+		if matched, _ := path.Match(pattern, envName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveEnvironments returns the names of all environments matching the names or glob patterns
+func ResolveEnvironments(apicClient apic.Client, patterns []string) ([]string, error) {
+	envNames := make(map[string]string)
+	for _, pattern := range patterns {
+		envNames[pattern] = pattern
+	}
+
+	e := management.NewEnvironment("")
+	envs, err := apicClient.GetAPIV1ResourceInstances(nil, e.GetKindLink())
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []string{}
+	for _, env := range envs {
+		if matchEnvironment(envNames, env.GetName()) {
+			matched = append(matched, env.GetName())
+		}
+	}
+	return matched, nil
+}
+
 func (t *serviceRegistry) readAPIServices(logger *logrus.Entry, envName string) {
 	s := management.NewAPIService("", envName)
-	services, err := t.apicClient.GetAPIV1ResourceInstances(nil, s.GetKindLink())
+	services, err := t.apicClient.GetAPIV1ResourceInstances(t.filter.params(""), s.GetKindLink())
 	if err != nil {
 		t.logger.WithError(err).Error("unable to read assets")
 	}
@@ -367,7 +431,7 @@ func (t *serviceRegistry) readAPIServiceRevisions(logger *logrus.Entry, refID, e
 	for _, revision := range revisions {
 		rev := management.NewAPIServiceRevision("", envName)
 		rev.FromInstance(revision)
-		if t.stripData {
+		if t.stripData && t.specsDir == "" {
 			rev.Spec.Definition.Type = "unstructured"
 			rev.Spec.Definition.Value = ""
 		}
@@ -409,7 +473,7 @@ func (t *serviceRegistry) readAPIServiceRevisionByName(logger *logrus.Entry, rev
 		return
 	}
 	r.FromInstance(revInst)
-	if t.stripData {
+	if t.stripData && t.specsDir == "" {
 		r.Spec.Definition.Type = "unstructured"
 		r.Spec.Definition.Value = ""
 	}
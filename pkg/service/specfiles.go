@@ -0,0 +1,108 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+)
+
+// SpecFilePrefix marks a revision definition value that references a spec file instead of holding the spec
+const SpecFilePrefix = "file:"
+
+var specFileExtensions = map[string]string{
+	"wsdl":     "wsdl",
+	"protobuf": "proto",
+	"raml":     "raml",
+	"graphql":  "graphql",
+}
+
+// specFileExtension returns the extension for the definition type, json or yaml specs are detected by their content
+func specFileExtension(defType string, spec []byte) string {
+	if ext, found := specFileExtensions[strings.ToLower(defType)]; found {
+		return ext
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(spec)), "{") {
+		return "json"
+	}
+	return "yaml"
+}
+
+// extractSpecFile writes the revision definition to <specsDir>/<env>/<service>/<revision>.<ext>, relative to baseDir,
+// and replaces the definition value with a reference to the file
+func extractSpecFile(baseDir, specsDir string, rev *management.APIServiceRevision) error {
+	if rev.Spec.Definition.Value == "" {
+		return nil
+	}
+	spec, err := base64.StdEncoding.DecodeString(rev.Spec.Definition.Value)
+	if err != nil {
+		return fmt.Errorf("unable to decode the definition of revision %s: %w", rev.Name, err)
+	}
+
+	ref := filepath.Join(specsDir, rev.Metadata.Scope.Name, rev.Spec.ApiService, rev.Name+"."+specFileExtension(rev.Spec.Definition.Type, spec))
+	fileName := filepath.Join(baseDir, ref)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0777); err != nil {
+		return err
+	}
+	if err := os.WriteFile(fileName, spec, 0777); err != nil {
+		return err
+	}
+	rev.Spec.Definition.Value = SpecFilePrefix + filepath.ToSlash(ref)
+	return nil
+}
+
+// InlineSpecFile replaces a spec file reference, relative to baseDir, with the encoded content of the file. A reference
+// to a file outside of baseDir is refused, so an edited export can not read any other file.
+func InlineSpecFile(baseDir string, rev *management.APIServiceRevision) error {
+	if !strings.HasPrefix(rev.Spec.Definition.Value, SpecFilePrefix) {
+		return nil
+	}
+	ref := strings.TrimPrefix(rev.Spec.Definition.Value, SpecFilePrefix)
+	fileName, err := specFilePath(baseDir, ref)
+	if err != nil {
+		return fmt.Errorf("unable to read the spec file of revision %s: %w", rev.Name, err)
+	}
+	spec, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("unable to read the spec file of revision %s: %w", rev.Name, err)
+	}
+	rev.Spec.Definition.Value = base64.StdEncoding.EncodeToString(spec)
+	return nil
+}
+
+// specFilePath returns the path of a spec file reference, relative to baseDir, when it resolves to a file within baseDir
+func specFilePath(baseDir, ref string) (string, error) {
+	if filepath.IsAbs(filepath.FromSlash(ref)) || filepath.VolumeName(ref) != "" {
+		return "", fmt.Errorf("spec file %s is not relative to the export", ref)
+	}
+	base, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", err
+	}
+	fileName := filepath.Join(base, filepath.FromSlash(ref))
+	if !isWithin(base, fileName) {
+		return "", fmt.Errorf("spec file %s is outside of the export directory %s", ref, baseDir)
+	}
+
+	// a symbolic link within the directory may still point outside of it
+	realBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", err
+	}
+	realFile, err := filepath.EvalSymlinks(fileName)
+	if err != nil {
+		return "", fmt.Errorf("spec file %s can not be read: %w", ref, err)
+	}
+	if !isWithin(realBase, realFile) {
+		return "", fmt.Errorf("spec file %s is outside of the export directory %s", ref, baseDir)
+	}
+	return fileName, nil
+}
+
+func isWithin(dir, fileName string) bool {
+	rel, err := filepath.Rel(dir, fileName)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpecFilePath(t *testing.T) {
+	root := t.TempDir()
+	baseDir := filepath.Join(root, "export")
+	specDir := filepath.Join(baseDir, "specs", "env", "svc")
+	if err := os.MkdirAll(specDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range []string{filepath.Join(specDir, "rev.yaml"), filepath.Join(root, "secret.yaml")} {
+		if err := os.WriteFile(fileName, []byte("openapi: 3.0.0"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "secret.yaml"), filepath.Join(specDir, "link.yaml")); err != nil {
+		t.Skipf("symbolic links not supported: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{name: "file in the export", ref: "specs/env/svc/rev.yaml", want: filepath.Join(specDir, "rev.yaml")},
+		{name: "clean path within the export", ref: "specs/env/../env/svc/rev.yaml", want: filepath.Join(specDir, "rev.yaml")},
+		{name: "parent directory", ref: "../secret.yaml", wantErr: true},
+		{name: "parent directory after a subdirectory", ref: "specs/../../secret.yaml", wantErr: true},
+		{name: "absolute path", ref: filepath.ToSlash(filepath.Join(root, "secret.yaml")), wantErr: true},
+		{name: "symbolic link outside of the export", ref: "specs/env/svc/link.yaml", wantErr: true},
+		{name: "missing file", ref: "specs/env/svc/missing.yaml", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := specFilePath(baseDir, tc.ref)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+)
+
+// ResetMetadata removes the metadata set by the server so that the resource may be created again,
+// in another scope or under another name
+func ResetMetadata(ri *v1.ResourceInstance) {
+	ri.Metadata.Audit = v1.AuditMetadata{}
+	ri.Metadata.References = []v1.Reference{}
+	ri.Metadata.ID = ""
+	ri.Metadata.ResourceVersion = ""
+	ri.Metadata.Scope.ID = ""
+	ri.Metadata.Scope.SelfLink = ""
+	ri.Metadata.SelfLink = ""
+	ri.Finalizers = make([]v1.Finalizer, 0)
+}
+
+// stripResource removes the org specific metadata from a resource so it may be applied elsewhere,
+// the owner is kept when keepOwner is set so that it can be remapped to a team in the target org
+func stripResource(ri *v1.ResourceInstance, keepOwner bool) {
+	ResetMetadata(ri)
+	if !keepOwner {
+		ri.Owner = nil
+	}
+	ri.Tags = []string{}
+	ri.Attributes = map[string]string{}
+}
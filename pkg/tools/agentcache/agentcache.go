@@ -0,0 +1,183 @@
+package agentcache
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"github.com/vivekschauhan/amplify-tool/pkg/log"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+	"github.com/vivekschauhan/amplify-tool/pkg/tools/dupes"
+)
+
+type Tool interface {
+	Run() error
+}
+
+type tool struct {
+	cfg     *Config
+	logger  *logrus.Logger
+	deleted *deletedResources
+	// the services deleted by the plan, and the instances and revisions it merged to a kept service, by kind/env/name
+	services map[string]struct{}
+	merged   map[string]struct{}
+}
+
+func NewTool(cfg *Config) Tool {
+	return &tool{
+		logger:   log.GetLogger(cfg.Level, cfg.Format),
+		cfg:      cfg,
+		deleted:  newDeletedResources(),
+		services: map[string]struct{}{},
+		merged:   map[string]struct{}{},
+	}
+}
+
+func (t *tool) Run() error {
+	t.logger.Info("Amplify Agent Cache Cleanup Tool")
+	if t.cfg.CacheFile == "" {
+		err := fmt.Errorf("the cache_file of the agent is required")
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	if t.cfg.PlanFile == "" {
+		// only the plan has the instances and revisions merged to a kept service, which must stay in the cache
+		err := fmt.Errorf("the plan_file of the duplicate tool is required")
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	if err := t.readPlan(); err != nil {
+		t.logger.WithError(err).Error("could not read plan file: stopping the tool")
+		return err
+	}
+	if t.cfg.BackupFile != "" {
+		if err := t.readBackup(); err != nil {
+			t.logger.WithError(err).Error("could not read backup file: stopping the tool")
+			return err
+		}
+	}
+	t.logger.WithField("resources", len(t.deleted.names)).Info("found deleted resources")
+
+	logger := t.logger.WithField("cacheFile", t.cfg.CacheFile)
+	buf, err := os.ReadFile(t.cfg.CacheFile)
+	if err != nil {
+		logger.WithError(err).Error("could not read agent cache: stopping the tool")
+		return err
+	}
+	var cache interface{}
+	if err := json.Unmarshal(buf, &cache); err != nil {
+		logger.WithError(err).Error("could not parse agent cache: stopping the tool")
+		return err
+	}
+
+	cleaned, removed := t.deleted.clean(cache)
+	logger.WithField("removed", removed).Info("cleaned agent cache")
+	if t.cfg.DryRun || removed == 0 {
+		return nil
+	}
+
+	backupFile := t.cfg.CacheBackupFile
+	if backupFile == "" {
+		backupFile = t.cfg.CacheFile + ".bak"
+	}
+	if err := os.WriteFile(backupFile, buf, 0644); err != nil {
+		logger.WithError(err).Error("could not write agent cache backup: stopping the tool")
+		return err
+	}
+	out, err := json.Marshal(cleaned)
+	if err != nil {
+		return err
+	}
+	outFile := t.cfg.OutFile
+	if outFile == "" {
+		outFile = t.cfg.CacheFile
+	}
+	if err := os.WriteFile(outFile, out, 0644); err != nil {
+		logger.WithError(err).Error("could not write cleaned agent cache")
+		return err
+	}
+	logger.WithField("outFile", outFile).WithField("backupFile", backupFile).Info("wrote cleaned agent cache")
+	return nil
+}
+
+// readBackup adds the services of a duplicate tool backup that the plan deletes, with their revisions and instances
+// that were not merged to the kept service, to the deleted resources. The backup has their IDs, and the revisions
+// that no instance used.
+func (t *tool) readBackup() error {
+	file, err := os.Open(t.cfg.BackupFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 256*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") {
+			continue
+		}
+		svcInfos := []*service.APIServiceInfo{}
+		if err := json.Unmarshal([]byte(line), &svcInfos); err != nil {
+			return err
+		}
+		for _, svcInfo := range svcInfos {
+			if svcInfo == nil || svcInfo.APIService == nil {
+				continue
+			}
+			svc := svcInfo.APIService
+			if _, found := t.services[resourceKey(svc.Kind, svc.Metadata.Scope.Name, svc.Name)]; !found {
+				continue
+			}
+			t.deleted.add(svc.Kind, svc.Metadata.Scope.Name, svc.Name, svc.Metadata.ID)
+			for _, rev := range svcInfo.APIServiceRevisions {
+				if _, found := t.merged[resourceKey(rev.Kind, rev.Metadata.Scope.Name, rev.Name)]; !found {
+					t.deleted.add(rev.Kind, rev.Metadata.Scope.Name, rev.Name, rev.Metadata.ID)
+				}
+			}
+			for _, inst := range svcInfo.APIServiceInstances {
+				if _, found := t.merged[resourceKey(inst.Kind, inst.Metadata.Scope.Name, inst.Name)]; !found {
+					t.deleted.add(inst.Kind, inst.Metadata.Scope.Name, inst.Name, inst.Metadata.ID)
+				}
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// readPlan adds the deleted services of a duplicate tool plan, and their instances and revisions that were not merged
+// to the kept service, to the deleted resources. The merged instances and revisions are kept.
+func (t *tool) readPlan() error {
+	p, err := dupes.ReadPlan(t.cfg.PlanFile)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range p.Actions {
+		deletedServices := map[string]struct{}{}
+		for _, s := range a.Steps {
+			if s.Type == dupes.StepDeleteService {
+				deletedServices[s.Service] = struct{}{}
+				t.services[resourceKey(management.APIServiceGVK().Kind, s.Env, s.Service)] = struct{}{}
+				t.deleted.add(management.APIServiceGVK().Kind, s.Env, s.Service, "")
+			}
+		}
+		for _, h := range a.Hashes {
+			if _, found := deletedServices[h.Service]; !found {
+				continue
+			}
+			if h.Result == dupes.HashMerge {
+				t.merged[resourceKey(management.APIServiceInstanceGVK().Kind, a.Env, h.Instance)] = struct{}{}
+				t.merged[resourceKey(management.APIServiceRevisionGVK().Kind, a.Env, h.Revision)] = struct{}{}
+				continue
+			}
+			t.deleted.add(management.APIServiceInstanceGVK().Kind, a.Env, h.Instance, "")
+			t.deleted.add(management.APIServiceRevisionGVK().Kind, a.Env, h.Revision, "")
+		}
+	}
+	return nil
+}
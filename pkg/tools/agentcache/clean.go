@@ -0,0 +1,105 @@
+package agentcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// deletedResources are the resources to remove from the agent cache, by kind/env/name and by ID
+type deletedResources struct {
+	names map[string]string
+	ids   map[string]struct{}
+}
+
+func newDeletedResources() *deletedResources {
+	return &deletedResources{
+		names: map[string]string{},
+		ids:   map[string]struct{}{},
+	}
+}
+
+func resourceKey(kind, env, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, env, name)
+}
+
+func (d *deletedResources) add(kind, env, name, id string) {
+	d.names[resourceKey(kind, env, name)] = id
+	if id != "" {
+		d.ids[id] = struct{}{}
+	}
+}
+
+// matches returns true when the value is, or wraps in an object field, a deleted resource
+func (d *deletedResources) matches(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		// cached objects may be held as serialized json
+		if !strings.HasPrefix(strings.TrimSpace(v), "{") {
+			return false
+		}
+		var obj interface{}
+		if err := json.Unmarshal([]byte(v), &obj); err != nil {
+			return false
+		}
+		return d.matches(obj)
+	case map[string]interface{}:
+		for _, field := range []string{"object", "Object"} {
+			if obj, found := v[field]; found && d.matches(obj) {
+				return true
+			}
+		}
+		return d.isDeleted(v)
+	}
+	return false
+}
+
+func (d *deletedResources) isDeleted(obj map[string]interface{}) bool {
+	kind, _ := obj["kind"].(string)
+	name, _ := obj["name"].(string)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if kind == "" || name == "" || metadata == nil {
+		return false
+	}
+	if id, _ := metadata["id"].(string); id != "" {
+		if _, found := d.ids[id]; found {
+			return true
+		}
+	}
+	scope, _ := metadata["scope"].(map[string]interface{})
+	env, _ := scope["name"].(string)
+	_, found := d.names[resourceKey(kind, env, name)]
+	return found
+}
+
+// clean removes the map entries and list items for deleted resources, or keyed by their ID, and returns the count
+func (d *deletedResources) clean(value interface{}) (interface{}, int) {
+	removed := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if _, found := d.ids[key]; found || d.matches(item) {
+				delete(v, key)
+				removed++
+				continue
+			}
+			cleaned, n := d.clean(item)
+			v[key] = cleaned
+			removed += n
+		}
+		return v, removed
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if d.matches(item) {
+				removed++
+				continue
+			}
+			cleaned, n := d.clean(item)
+			items = append(items, cleaned)
+			removed += n
+		}
+		return items, removed
+	}
+	return value, removed
+}
@@ -0,0 +1,85 @@
+package agentcache
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	deleted := newDeletedResources()
+	deleted.add("APIService", "env", "petstore", "svc-1")
+	deleted.add("APIServiceInstance", "env", "petstore-inst", "")
+
+	tests := []struct {
+		name        string
+		cache       string
+		want        string
+		wantRemoved int
+	}{
+		{
+			name:  "nothing deleted",
+			cache: `{"apiMap": {"svc-2": {"kind": "APIService", "name": "orders", "metadata": {"id": "svc-2", "scope": {"name": "env"}}}}}`,
+			want:  `{"apiMap": {"svc-2": {"kind": "APIService", "name": "orders", "metadata": {"id": "svc-2", "scope": {"name": "env"}}}}}`,
+		},
+		{
+			name:        "map entry keyed by the ID",
+			cache:       `{"apiMap": {"svc-1": {"hash": "abc"}, "svc-2": {"hash": "def"}}}`,
+			want:        `{"apiMap": {"svc-2": {"hash": "def"}}}`,
+			wantRemoved: 1,
+		},
+		{
+			name:        "resource matched by ID",
+			cache:       `{"apis": {"a": {"kind": "APIService", "name": "renamed", "metadata": {"id": "svc-1", "scope": {"name": "other"}}}}}`,
+			want:        `{"apis": {}}`,
+			wantRemoved: 1,
+		},
+		{
+			name:        "resource matched by kind, env and name",
+			cache:       `{"instances": [{"kind": "APIServiceInstance", "name": "petstore-inst", "metadata": {"scope": {"name": "env"}}}, {"kind": "APIServiceInstance", "name": "petstore-inst", "metadata": {"scope": {"name": "other"}}}]}`,
+			want:        `{"instances": [{"kind": "APIServiceInstance", "name": "petstore-inst", "metadata": {"scope": {"name": "other"}}}]}`,
+			wantRemoved: 1,
+		},
+		{
+			name:        "resource wrapped in an object field",
+			cache:       `{"items": {"a": {"Object": {"kind": "APIService", "name": "petstore", "metadata": {"scope": {"name": "env"}}}}, "b": {"object": {"kind": "APIService", "name": "orders", "metadata": {"scope": {"name": "env"}}}}}}`,
+			want:        `{"items": {"b": {"object": {"kind": "APIService", "name": "orders", "metadata": {"scope": {"name": "env"}}}}}}`,
+			wantRemoved: 1,
+		},
+		{
+			name:        "resource serialized as a json string",
+			cache:       `{"items": ["{\"kind\": \"APIService\", \"name\": \"petstore\", \"metadata\": {\"scope\": {\"name\": \"env\"}}}", "not json"]}`,
+			want:        `{"items": ["not json"]}`,
+			wantRemoved: 1,
+		},
+		{
+			name:        "nested entries",
+			cache:       `{"a": {"b": [{"c": {"svc-1": 1, "svc-3": 3}}]}}`,
+			want:        `{"a": {"b": [{"c": {"svc-3": 3}}]}}`,
+			wantRemoved: 1,
+		},
+		{
+			name:  "same name of another kind",
+			cache: `[{"kind": "APIServiceRevision", "name": "petstore", "metadata": {"scope": {"name": "env"}}}]`,
+			want:  `[{"kind": "APIServiceRevision", "name": "petstore", "metadata": {"scope": {"name": "env"}}}]`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var cache, want interface{}
+			if err := json.Unmarshal([]byte(tc.cache), &cache); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			got, removed := deleted.clean(cache)
+			if removed != tc.wantRemoved {
+				t.Errorf("removed %d, want %d", removed, tc.wantRemoved)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package agentcache
+
+import "github.com/vivekschauhan/amplify-tool/pkg/tools"
+
+// Config the configuration for the Watch client
+type Config struct {
+	tools.Config
+	CacheFile       string `mapstructure:"cache_file"`
+	OutFile         string `mapstructure:"out_file"`
+	CacheBackupFile string `mapstructure:"cache_backup_file"`
+	PlanFile        string `mapstructure:"plan_file"`
+	BackupFile      string `mapstructure:"backup_file"`
+}
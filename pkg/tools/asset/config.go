@@ -7,4 +7,9 @@ type Config struct {
 	tools.Config
 	ServiceMappingFile string `mapstructure:"service_mapping_file"`
 	ProductCatalogFile string `mapstructure:"product_catalog_file"`
+	Assets             string `mapstructure:"assets"`
+	ExcludeAssets      string `mapstructure:"exclude_assets"`
+	ErrorMatch         string `mapstructure:"error_match"`
+	Journal            string `mapstructure:"journal"`
+	AppendJournal      bool   `mapstructure:"append_journal"`
 }
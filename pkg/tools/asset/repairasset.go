@@ -20,6 +20,8 @@ type tool struct {
 	productCatalog  service.ProductCatalog
 	assetCatalog    service.AssetCatalog
 	serviceRegistry service.ServiceRegistry
+	journal         *service.Journal
+	initErr         error
 }
 
 func NewTool(cfg *Config) Tool {
@@ -28,9 +30,16 @@ func NewTool(cfg *Config) Tool {
 	utillog.GlobalLoggerConfig.Level(cfg.Level).
 		Format(cfg.Format).
 		Apply()
+	selector, initErr := service.NewRepairSelector(cfg.Assets, cfg.ExcludeAssets, cfg.ErrorMatch)
+
+	// nothing is changed in a dry run, so there is nothing to journal
+	var journal *service.Journal
+	if initErr == nil && cfg.Journal != "" && !cfg.DryRun {
+		journal, initErr = service.NewJournal(logger, cfg.Journal, cfg.AppendJournal)
+	}
 	serviceRegistry := service.NewServiceRegistry(logger, apicClient, cfg.DryRun, service.WithMappingFile(cfg.ServiceMappingFile))
-	assetCatalog := service.NewAssetCatalog(logger, apicClient, cfg.DryRun, serviceRegistry)
-	productCatalog := service.NewProductCatalog(logger, assetCatalog, apicClient, cfg.ProductCatalogFile, cfg.DryRun)
+	assetCatalog := service.NewAssetCatalog(logger, apicClient, cfg.DryRun, serviceRegistry, service.WithRepairSelector(selector), service.WithJournal(journal))
+	productCatalog := service.NewProductCatalog(logger, assetCatalog, apicClient, cfg.ProductCatalogFile, cfg.DryRun, service.WithProductRepairSelector(selector), service.WithProductJournal(journal))
 	return &tool{
 		logger:          logger,
 		cfg:             cfg,
@@ -38,11 +47,18 @@ func NewTool(cfg *Config) Tool {
 		serviceRegistry: serviceRegistry,
 		assetCatalog:    assetCatalog,
 		productCatalog:  productCatalog,
+		journal:         journal,
+		initErr:         initErr,
 	}
 }
 
 func (t *tool) Run() error {
 	t.logger.Info("Amplify Asset Tool")
+	if t.initErr != nil {
+		t.logger.WithError(t.initErr).Error("stopping the repair")
+		return t.initErr
+	}
+	defer t.journal.Close()
 	err := t.Read()
 	t.Write()
 	if err != nil {
@@ -58,12 +74,11 @@ func (t *tool) Run() error {
 func (t *tool) Read() error {
 	t.serviceRegistry.ReadServices()
 	err := t.assetCatalog.ReadAssets(false)
-	t.productCatalog.ReadProducts()
 	if err != nil {
 		return err
 	}
-
-	return nil
+	// the products in error are repaired with the assets, so they must all be read
+	return t.productCatalog.ReadProducts()
 }
 
 func (t *tool) Write() error {
@@ -0,0 +1,236 @@
+package clone
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Axway/agent-sdk/pkg/apic"
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	utillog "github.com/Axway/agent-sdk/pkg/util/log"
+	"github.com/sirupsen/logrus"
+	"github.com/vivekschauhan/amplify-tool/pkg/log"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+	"github.com/vivekschauhan/amplify-tool/pkg/tools"
+)
+
+type Tool interface {
+	Run() error
+}
+
+type tool struct {
+	apicClient apic.Client
+	cfg        *Config
+	logger     *logrus.Logger
+	failed     int
+}
+
+func NewTool(cfg *Config) Tool {
+	logger := log.GetLogger(cfg.Level, cfg.Format)
+	apicClient, _ := tools.CreateAPICClient(&cfg.Config)
+	utillog.GlobalLoggerConfig.Level(cfg.Level).
+		Format(cfg.Format).
+		Apply()
+	return &tool{
+		logger:     logger,
+		cfg:        cfg,
+		apicClient: apicClient,
+	}
+}
+
+func (t *tool) Run() error {
+	t.logger.Info("Amplify Environment Clone Tool")
+	if t.cfg.Source == "" || t.cfg.Target == "" {
+		err := fmt.Errorf("both the source and target environments are required")
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	if t.cfg.Source == t.cfg.Target {
+		err := fmt.Errorf("the source and target environments must be different")
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	logger := t.logger.WithField("source", t.cfg.Source).WithField("target", t.cfg.Target)
+
+	if err := t.createTargetEnvironment(logger); err != nil {
+		logger.WithError(err).Error("could not create the target environment: stopping the tool")
+		return err
+	}
+
+	serviceRegistry := service.NewServiceRegistry(t.logger, t.apicClient, t.cfg.DryRun,
+		service.WithEnvironment(t.cfg.Source),
+		service.WithGetAllRevisions(true),
+		service.WithGetInstances(),
+		service.WithIncludeData(true),
+	)
+	serviceRegistry.ReadServices()
+	resources := serviceRegistry.GetServicesOutput()
+
+	if t.cfg.CloneAssets {
+		assetCatalog := service.NewAssetCatalog(t.logger, t.apicClient, t.cfg.DryRun, serviceRegistry, service.WithFilterUsingRegistry(), service.ForExport())
+		assetCatalog.ReadAssets(false)
+		resources = append(resources, assetCatalog.GetAssetOutput()...)
+	}
+
+	cloned := t.cloneResources(resources)
+	logger.WithField("resources", len(cloned)).Info("cloning environment")
+	for _, ri := range service.SortByKind(cloned) {
+		t.apply(ri)
+	}
+
+	if t.failed > 0 {
+		err := fmt.Errorf("%d resources could not be cloned", t.failed)
+		logger.WithError(err).Error("environment clone incomplete")
+		return err
+	}
+	logger.Info("environment cloned")
+	return nil
+}
+
+// createTargetEnvironment creates the target environment from the source environment, when it does not exist
+func (t *tool) createTargetEnvironment(logger *logrus.Entry) error {
+	source := management.NewEnvironment(t.cfg.Source)
+	ri, err := t.apicClient.GetResource(source.GetSelfLink())
+	if err != nil {
+		return fmt.Errorf("unable to read the source environment %s: %s", t.cfg.Source, err)
+	}
+
+	target := management.NewEnvironment(t.cfg.Target)
+	targetRI, _ := target.AsInstance()
+	existing, err := service.ExistingResource(t.apicClient, targetRI)
+	if err != nil {
+		return fmt.Errorf("unable to check the target environment %s: %s", t.cfg.Target, err)
+	}
+	if existing != nil {
+		logger.Info("target environment exists")
+		return nil
+	}
+
+	env := management.NewEnvironment("")
+	env.FromInstance(ri)
+	env.Name = t.cfg.Target
+	env.Title = t.cfg.Target
+	envRI, _ := env.AsInstance()
+	service.ResetMetadata(envRI)
+	if t.cfg.DryRun {
+		logger.Info("dry run, target environment not created")
+		return nil
+	}
+	if _, err := t.apicClient.CreateResourceInstance(envRI); err != nil {
+		return err
+	}
+	logger.Info("created target environment")
+	return nil
+}
+
+// cloneResources moves the resources to the target environment, and cloned assets to a name for the target,
+// rewriting the references between them to match
+func (t *tool) cloneResources(resources []v1.Interface) []*v1.ResourceInstance {
+	cloned := []*v1.ResourceInstance{}
+	accessRequestDefs := map[string]struct{}{}
+	credentialRequestDefs := map[string]struct{}{}
+	for _, res := range resources {
+		ri, err := res.AsInstance()
+		if err != nil {
+			t.logger.WithError(err).Error("unable to read resource")
+			continue
+		}
+		service.ResetMetadata(ri)
+
+		switch ri.Kind {
+		case management.APIServiceGVK().Kind, management.APIServiceRevisionGVK().Kind:
+			ri.Metadata.Scope.Name = t.cfg.Target
+		case management.APIServiceInstanceGVK().Kind:
+			inst := management.NewAPIServiceInstance("", t.cfg.Target)
+			inst.FromInstance(ri)
+			inst.Metadata.Scope.Name = t.cfg.Target
+			inst.Spec.ApiServiceRevision = t.targetReference(inst.Spec.ApiServiceRevision)
+			// the request definitions are cloned with the same names, so the references are kept
+			if inst.Spec.AccessRequestDefinition != "" {
+				accessRequestDefs[inst.Spec.AccessRequestDefinition] = struct{}{}
+			}
+			for _, name := range inst.Spec.CredentialRequestDefinitions {
+				credentialRequestDefs[name] = struct{}{}
+			}
+			ri, _ = inst.AsInstance()
+		case catalog.AssetGVK().Kind:
+			ri.Name = t.assetName(ri.Name)
+			if ri.Title != "" {
+				ri.Title = fmt.Sprintf("%s (%s)", ri.Title, t.cfg.Target)
+			}
+		case catalog.AssetMappingGVK().Kind:
+			am := catalog.NewAssetMapping("", t.assetName(ri.Metadata.Scope.Name))
+			am.FromInstance(ri)
+			am.Metadata.Scope.Name = t.assetName(ri.Metadata.Scope.Name)
+			am.Spec.Inputs.ApiService = t.targetReference(am.Spec.Inputs.ApiService)
+			am.Spec.Inputs.ApiServiceRevision = t.targetReference(am.Spec.Inputs.ApiServiceRevision)
+			ri, _ = am.AsInstance()
+		}
+		cloned = append(cloned, ri)
+	}
+
+	for name := range accessRequestDefs {
+		cloned = t.appendRequestDefinition(cloned, management.NewAccessRequestDefinition(name, t.cfg.Source).GetSelfLink())
+	}
+	for name := range credentialRequestDefs {
+		cloned = t.appendRequestDefinition(cloned, management.NewCredentialRequestDefinition(name, t.cfg.Source).GetSelfLink())
+	}
+	return cloned
+}
+
+// appendRequestDefinition reads an access or credential request definition, referenced by a source instance, and
+// appends its clone in the target environment
+func (t *tool) appendRequestDefinition(cloned []*v1.ResourceInstance, selfLink string) []*v1.ResourceInstance {
+	ri, err := t.apicClient.GetResource(selfLink)
+	if err != nil || ri == nil {
+		t.logger.WithError(err).WithField("selfLink", selfLink).Error("unable to read request definition")
+		t.failed++
+		return cloned
+	}
+	service.ResetMetadata(ri)
+	ri.Metadata.Scope.Name = t.cfg.Target
+	return append(cloned, ri)
+}
+
+// assetName returns the name of the clone of an asset in the target environment
+func (t *tool) assetName(name string) string {
+	return fmt.Sprintf("%s-%s", name, t.cfg.Target)
+}
+
+// targetReference rewrites the source environment of a reference in either the group/env/name or env/name form
+func (t *tool) targetReference(ref string) string {
+	elements := strings.Split(ref, "/")
+	envIndex := len(elements) - 2
+	if envIndex >= 0 && elements[envIndex] == t.cfg.Source {
+		elements[envIndex] = t.cfg.Target
+	}
+	return strings.Join(elements, "/")
+}
+
+func (t *tool) apply(ri *v1.ResourceInstance) {
+	logger := t.logger.
+		WithField("kind", ri.Kind).
+		WithField("scope", ri.Metadata.Scope.Name).
+		WithField("name", ri.Name)
+
+	existing, err := service.ExistingResource(t.apicClient, ri)
+	if err != nil {
+		logger.WithError(err).Error("unable to check the resource in the target environment")
+		t.failed++
+		return
+	}
+	exists := existing != nil
+	logger = logger.WithField("exists", exists)
+	if t.cfg.DryRun {
+		logger.Info("dry run, resource not cloned")
+		return
+	}
+
+	if err := service.ApplyResource(t.apicClient, ri, exists); err != nil {
+		logger.WithError(err).Error("unable to clone resource")
+		t.failed++
+		return
+	}
+	logger.Info("cloned resource")
+}
@@ -0,0 +1,11 @@
+package clone
+
+import "github.com/vivekschauhan/amplify-tool/pkg/tools"
+
+// Config the configuration for the Watch client
+type Config struct {
+	tools.Config
+	Source      string `mapstructure:"source"`
+	Target      string `mapstructure:"target"`
+	CloneAssets bool   `mapstructure:"clone_assets"`
+}
@@ -72,16 +72,18 @@ func CreateAPICClient(cfg *Config) (apic.Client, auth.PlatformTokenGetter) {
 	urls := regionalSettingsMap[centralCfg.GetRegion()]
 	if cfg.SingleURL == "" {
 		cfg.SingleURL = urls.SingleURL
-		centralCfg.SingleURL = urls.SingleURL
 	}
+	centralCfg.SingleURL = cfg.SingleURL
 	if cfg.URL == "" {
 		cfg.URL = urls.CentralURL
-		centralCfg.URL = urls.CentralURL
 	}
+	centralCfg.URL = cfg.URL
 	if cfg.PlatformURL == "" {
 		cfg.PlatformURL = urls.PlatformURL
-		centralCfg.PlatformURL = urls.PlatformURL
 	}
+	centralCfg.PlatformURL = cfg.PlatformURL
+	// the requests are made to the org of the config, e.g. the target org of an import
+	centralCfg.TenantID = cfg.OrgID
 	if cfg.TraceabilityHost == "" {
 		// convert to http
 		cfg.TraceabilityHost = strings.Split(urls.TraceabilityHost, ":")[0] + ":443"
@@ -0,0 +1,89 @@
+package dupes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+)
+
+// subscriptions are the access requests and credentials of an environment
+type subscriptions struct {
+	accessRequests []*management.AccessRequest
+	credentials    []*management.Credential
+}
+
+// readSubscriptions reads, once, the access requests and credentials of the environment
+func (t *tool) readSubscriptions(env string) (*subscriptions, error) {
+	if subs, found := t.subscriptions[env]; found {
+		return subs, nil
+	}
+
+	subs := &subscriptions{}
+	ar := management.NewAccessRequest("", env)
+	ris, err := t.apicClient.GetAPIV1ResourceInstances(nil, ar.GetKindLink())
+	if err != nil {
+		return nil, err
+	}
+	for _, ri := range ris {
+		ar := management.NewAccessRequest("", env)
+		ar.FromInstance(ri)
+		subs.accessRequests = append(subs.accessRequests, ar)
+	}
+
+	cred := management.NewCredential("", env)
+	ris, err = t.apicClient.GetAPIV1ResourceInstances(nil, cred.GetKindLink())
+	if err != nil {
+		return nil, err
+	}
+	for _, ri := range ris {
+		cred := management.NewCredential("", env)
+		cred.FromInstance(ri)
+		subs.credentials = append(subs.credentials, cred)
+	}
+
+	t.subscriptions[env] = subs
+	return subs, nil
+}
+
+// blockers returns the reasons the service can not be deleted safely. These are the quotas of product plans
+// referencing the asset resources of its instances and the access requests, with their managed applications
+// and credentials, bound to its instances.
+func (t *tool) blockers(env string, svcInfo *service.APIServiceInfo) ([]string, error) {
+	subs, err := t.readSubscriptions(env)
+	if err != nil {
+		return nil, err
+	}
+
+	blockers := []string{}
+	apps := map[string]struct{}{}
+	for _, inst := range sortedInstances(svcInfo) {
+		for _, nameWithScope := range t.assetCatalog.AssetsForInstance(inst.Group, env, inst.Name) {
+			elements := strings.SplitN(nameWithScope, "/", 2)
+			if len(elements) != 2 {
+				continue
+			}
+			for _, quota := range t.productCatalog.QuotasForAssetResource(elements[0], elements[1]) {
+				blockers = append(blockers, fmt.Sprintf("quota %s references asset resource %s of instance %s", quota, nameWithScope, inst.Name))
+			}
+		}
+		for _, ar := range subs.accessRequests {
+			if ar.Spec.ApiServiceInstance != inst.Name {
+				continue
+			}
+			apps[ar.Spec.ManagedApplication] = struct{}{}
+			blockers = append(blockers, fmt.Sprintf("access request %s of managed application %s is bound to instance %s", ar.Name, ar.Spec.ManagedApplication, inst.Name))
+		}
+	}
+
+	credentials := []string{}
+	for _, cred := range subs.credentials {
+		if _, found := apps[cred.Spec.ManagedApplication]; found {
+			credentials = append(credentials, fmt.Sprintf("credential %s of managed application %s", cred.Name, cred.Spec.ManagedApplication))
+		}
+	}
+	sort.Strings(credentials)
+	return append(blockers, credentials...), nil
+}
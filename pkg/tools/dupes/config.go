@@ -7,7 +7,15 @@ import (
 // Config the configuration for the Watch client
 type Config struct {
 	tools.Config
-	OutFile      string `mapstructure:"out_file"`
-	BackupFile   string `mapstructure:"backup_file"`
-	Environments string `mapstructure:"environments"`
+	OutFile          string `mapstructure:"out_file"`
+	BackupFile       string `mapstructure:"backup_file"`
+	Environments     string `mapstructure:"environments"`
+	Execute          bool   `mapstructure:"execute"`
+	GroupBy          string `mapstructure:"group_by"`
+	CrossEnvironment bool   `mapstructure:"cross_environment"`
+	PlanFile         string `mapstructure:"plan_file"`
+	FromPlan         string `mapstructure:"from_plan"`
+	ScriptFormat     string `mapstructure:"script_format"`
+	ScriptFile       string `mapstructure:"script_file"`
+	HTMLReport       string `mapstructure:"html_report"`
 }
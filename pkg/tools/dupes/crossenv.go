@@ -0,0 +1,169 @@
+package dupes
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Axway/agent-sdk/pkg/apic/definitions"
+	"github.com/Axway/agent-sdk/pkg/util"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+)
+
+// envService is a service in one of the environments of a cross environment group
+type envService struct {
+	env     string
+	name    string
+	svcInfo *service.APIServiceInfo
+	assets  int
+	agent   string
+}
+
+func (s envService) String() string {
+	return fmt.Sprintf("%s/%s", s.env, s.name)
+}
+
+// findCrossEnvDupes groups the services of all environments by the same key and reports the APIs found in more than one
+func (t *tool) findCrossEnvDupes() {
+	t.logger.Debug("starting to find possible duplicates across environments")
+	services := map[string]envService{}
+	serviceKeys := map[string][]string{}
+	for _, env := range t.serviceRegistry.GetEnvs() {
+		for name, svcInfo := range t.serviceRegistry.GetAPIServicesInfo(env) {
+			svcInfo := svcInfo
+			keys, _ := t.groupKeys(&svcInfo)
+			if len(keys) == 0 {
+				continue
+			}
+			s := envService{
+				env:     env,
+				name:    name,
+				svcInfo: &svcInfo,
+				assets:  t.countAssets(env, &svcInfo),
+				agent:   serviceAgent(env, &svcInfo),
+			}
+			services[s.String()] = s
+			serviceKeys[s.String()] = keys
+		}
+	}
+	grouping := map[string][]envService{}
+	for key, names := range groupByKeys(serviceKeys) {
+		for _, name := range names {
+			grouping[key] = append(grouping[key], services[name])
+		}
+	}
+
+	keys := make([]string, 0, len(grouping))
+	for key := range grouping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		group := grouping[key]
+		envs := map[string]struct{}{}
+		agents := map[string]struct{}{}
+		for _, s := range group {
+			envs[s.env] = struct{}{}
+			agents[s.agent] = struct{}{}
+		}
+		if len(envs) <= 1 {
+			continue
+		}
+		// an agent discovers into a single environment, so copies from one agent are not a misconfiguration
+		if len(agents) <= 1 {
+			t.logger.WithField("groupKey", key).WithField("envs", len(envs)).Debug("API in multiple environments from a single agent, not reported")
+			continue
+		}
+		t.handleCrossEnvGroup(key, group, len(envs))
+	}
+}
+
+// handleCrossEnvGroup reports an API found in several environments and suggests the environment that should own it
+func (t *tool) handleCrossEnvGroup(groupKey string, group []envService, numEnvs int) {
+	sort.Slice(group, func(i, j int) bool {
+		return group[i].String() < group[j].String()
+	})
+	actionString := fmt.Sprintf("%04d", t.actionIndex)
+	t.actionIndex++
+
+	envAssets := map[string]int{}
+	for _, s := range group {
+		envAssets[s.env] += s.assets
+	}
+	owner := suggestOwner(group, envAssets)
+	t.logger.
+		WithField("groupKey", groupKey).
+		WithField("envs", numEnvs).
+		WithField("ownerEnv", owner).
+		Info("found API in multiple environments")
+
+	act := &Action{
+		ID:       actionString,
+		Env:      owner,
+		GroupKey: groupKey,
+		Removed:  []string{},
+		Assets:   map[string]int{},
+		Hashes:   []HashResult{},
+		Services: []string{},
+		OwnerEnv: owner,
+		Review:   fmt.Sprintf("API found in %d environments, suggested owner environment %s", numEnvs, owner),
+		Steps:    []Step{},
+	}
+
+	t.output = append(t.output, sep)
+	t.output = append(t.output, fmt.Sprintf("#\tACTION %s: The following services are the same API discovered into %d environments, suggested owner environment %s", actionString, numEnvs, owner))
+	for _, s := range group {
+		act.Services = append(act.Services, s.String())
+		act.Assets[s.String()] = s.assets
+		t.output = append(t.output, fmt.Sprintf("#\t\t%v has %v assets, discovered by %v", s, s.assets, s.agent))
+	}
+	t.output = append(t.output, "#\tReview the agents discovering into the other environments before removing the services from them")
+	t.output = append(t.output, sep)
+	t.output = append(t.output, "")
+	t.actions = append(t.actions, act)
+}
+
+// serviceAgent returns the agent that created the service, from its x-agent-details or else the user that created it.
+// When neither is set the agent is taken to be the one of the environment.
+func serviceAgent(env string, svcInfo *service.APIServiceInfo) string {
+	if agent := util.GetAgentDetailStrings(svcInfo.APIService)[definitions.AttrCreatedBy]; agent != "" {
+		return agent
+	}
+	if user := svcInfo.APIService.GetMetadata().Audit.CreateUserID; user != "" {
+		return user
+	}
+	return "unknown agent of " + env
+}
+
+// suggestOwner returns the environment with the most assets using the API, then the most copies of it, and then the
+// environment of the oldest copy
+func suggestOwner(group []envService, envAssets map[string]int) string {
+	envServices := map[string]int{}
+	envOldest := map[string]time.Time{}
+	for _, s := range group {
+		envServices[s.env]++
+		created := time.Time(s.svcInfo.APIService.GetMetadata().Audit.CreateTimestamp)
+		if oldest, found := envOldest[s.env]; !found || created.Before(oldest) {
+			envOldest[s.env] = created
+		}
+	}
+
+	envs := make([]string, 0, len(envServices))
+	for env := range envServices {
+		envs = append(envs, env)
+	}
+	sort.Slice(envs, func(i, j int) bool {
+		a, b := envs[i], envs[j]
+		if envAssets[a] != envAssets[b] {
+			return envAssets[a] > envAssets[b]
+		}
+		if envServices[a] != envServices[b] {
+			return envServices[a] > envServices[b]
+		}
+		if !envOldest[a].Equal(envOldest[b]) {
+			return envOldest[a].Before(envOldest[b])
+		}
+		return a < b
+	})
+	return envs[0]
+}
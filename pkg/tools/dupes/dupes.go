@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/Axway/agent-sdk/pkg/apic"
-	"github.com/Axway/agent-sdk/pkg/apic/definitions"
 	"github.com/Axway/agent-sdk/pkg/util"
 	utillog "github.com/Axway/agent-sdk/pkg/util/log"
 	"github.com/sirupsen/logrus"
@@ -33,12 +32,15 @@ type tool struct {
 	logger          *logrus.Logger
 	serviceRegistry service.ServiceRegistry
 	assetCatalog    service.AssetCatalog
-	// productCatalog  service.ProductCatalog
-	actionIndex int
-	output      []string
-	outFile     string
-	backup      []string
-	backupFile  string
+	productCatalog  service.ProductCatalog
+	actionIndex     int
+	output          []string
+	outFile         string
+	backup          []string
+	backupFile      string
+	actions         []*Action
+	groupBy         []string
+	subscriptions   map[string]*subscriptions
 }
 
 func NewTool(cfg *Config) Tool {
@@ -47,38 +49,62 @@ func NewTool(cfg *Config) Tool {
 	utillog.GlobalLoggerConfig.Level(cfg.Level).
 		Format(cfg.Format).
 		Apply()
-	serviceRegistry := service.NewServiceRegistry(logger, apicClient, cfg.DryRun, service.WithGetInstances())
+	// every revision is only read for the backup, finding the duplicates needs the revisions used by the instances
+	getAllRevisions := cfg.BackupFile != ""
+	serviceRegistry := service.NewServiceRegistry(logger, apicClient, cfg.DryRun, service.WithGetInstances(), service.WithGetAllRevisions(getAllRevisions))
 	if len(cfg.Environments) > 0 {
 		envs := strings.Split(cfg.Environments, ",")
 		for i := range envs {
 			envs[i] = strings.Trim(envs[i], " ")
 		}
-		serviceRegistry = service.NewServiceRegistry(logger, apicClient, cfg.DryRun, service.WithGetInstances(), service.WithEnvironments(envs))
+		serviceRegistry = service.NewServiceRegistry(logger, apicClient, cfg.DryRun, service.WithGetInstances(), service.WithGetAllRevisions(getAllRevisions), service.WithEnvironments(envs))
 	}
 	assetCatalog := service.NewAssetCatalog(logger, apicClient, cfg.DryRun, serviceRegistry)
-	// productCatalog := service.NewProductCatalog(logger, assetCatalog, apicClient, "", cfg.DryRun)
+	productCatalog := service.NewProductCatalog(logger, assetCatalog, apicClient, "", cfg.DryRun)
 	return &tool{
 		logger:          logger,
 		cfg:             cfg,
 		apicClient:      apicClient,
 		serviceRegistry: serviceRegistry,
 		assetCatalog:    assetCatalog,
-		// productCatalog:  productCatalog,
-		actionIndex: 0,
-		output:      []string{},
-		outFile:     cfg.OutFile,
-		backup:      []string{},
-		backupFile:  cfg.BackupFile,
+		productCatalog:  productCatalog,
+		actionIndex:     0,
+		output:          []string{},
+		outFile:         cfg.OutFile,
+		backup:          []string{},
+		backupFile:      cfg.BackupFile,
+		actions:         []*Action{},
+		subscriptions:   map[string]*subscriptions{},
 	}
 }
 
 func (t *tool) Run() error {
 	t.logger.Info("Amplify Duplication Tool")
-	err := t.Read()
+	if t.cfg.Execute && t.backupFile == "" {
+		err := fmt.Errorf("a backup_file is required to execute the actions")
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	if _, found := scriptFiles[t.cfg.ScriptFormat]; t.cfg.ScriptFormat != "" && !found {
+		err := fmt.Errorf("unknown script_format %q, expected bash or powershell", t.cfg.ScriptFormat)
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	groupBy, err := parseGroupBy(t.cfg.GroupBy)
+	if err != nil {
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	t.groupBy = groupBy
+
+	err = t.Read()
 	if err != nil {
 		t.logger.WithError(err).Error("could not read resources: stopping the tool")
 		return err
 	}
+	if t.cfg.FromPlan != "" {
+		return t.runPlan()
+	}
 	// err = t.Write()
 	// if err != nil {
 	// 	t.logger.WithError(err).Error("could not write resources: stopping the tool")
@@ -91,7 +117,10 @@ func (t *tool) Read() error {
 	t.logger.Debug("gathering resources from amplify")
 	t.serviceRegistry.ReadServices()
 	t.assetCatalog.ReadAssets(false)
-	// t.productCatalog.ReadProducts()
+	// the quotas of the products block deleting the services they use, so they must all be read
+	if err := t.productCatalog.ReadProducts(); err != nil {
+		return fmt.Errorf("unable to check the product quotas of the services: %s", err)
+	}
 
 	// cycle through all envs
 	return nil
@@ -105,17 +134,29 @@ func (t *tool) findDupes() error {
 		grouping := t.groupServicesInEnv(env)
 		logger.WithField("groups", grouping).Debug("finished grouping for env")
 
-		// process each grouping
-		for key, group := range grouping {
-			logger = logger.WithField("groupKey", key)
+		// process each grouping, in key order so that the action IDs are stable
+		keys := make([]string, 0, len(grouping))
+		for key := range grouping {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			group := grouping[key]
 			if len(group) <= 1 {
 				continue
 			}
-			logger.WithField("copies", len(group)).Debug("found duplicates")
-			t.handleGroup(logger, env, group)
+			logger.WithField("groupKey", key).WithField("copies", len(group)).Debug("found duplicates")
+			t.handleGroup(logger.WithField("groupKey", key), env, key, group)
 		}
 	}
+	if t.cfg.CrossEnvironment {
+		t.findCrossEnvDupes()
+	}
+	return t.writeOutput()
+}
 
+// writeOutput writes the actions log, plan and backup and executes the actions when set to
+func (t *tool) writeOutput() error {
 	output := strings.Join(t.output, "\n")
 	if t.outFile == "" || t.cfg.DryRun {
 		fmt.Print(output)
@@ -127,14 +168,38 @@ func (t *tool) findDupes() error {
 	if t.outFile != "" {
 		os.WriteFile(t.outFile, []byte(output), 0777)
 	}
+	if t.cfg.PlanFile != "" {
+		if err := writePlan(t.cfg.PlanFile, &Plan{Actions: t.actions}); err != nil {
+			t.logger.WithError(err).Error("could not write plan file")
+			return err
+		}
+	}
+	if t.cfg.HTMLReport != "" {
+		if err := t.writeHTMLReport(); err != nil {
+			t.logger.WithError(err).Error("could not write html report")
+			return err
+		}
+	}
+	if t.cfg.ScriptFormat != "" {
+		if err := t.writeScript(); err != nil {
+			t.logger.WithError(err).Error("could not write script file")
+			return err
+		}
+	}
 	if t.backupFile != "" {
 		backup := strings.Join(t.backup, "\n")
-		os.WriteFile(t.backupFile, []byte(backup), 0777)
+		if err := os.WriteFile(t.backupFile, []byte(backup), 0777); err != nil {
+			t.logger.WithError(err).Error("could not write backup file")
+			return err
+		}
+	}
+	if t.cfg.Execute {
+		return t.execute()
 	}
 	return nil
 }
 
-func (t *tool) handleGroup(logger *logrus.Entry, env string, services []string) {
+func (t *tool) handleGroup(logger *logrus.Entry, env, groupKey string, services []string) {
 	sort.Strings(services)
 	actionString := fmt.Sprintf("%04d", t.actionIndex)
 
@@ -159,44 +224,42 @@ func (t *tool) handleGroup(logger *logrus.Entry, env string, services []string)
 			serviceToKeep = service
 		}
 
-		itemToAssets[service] = 0
-		for _, inst := range svcInfo.APIServiceInstances {
-			assets := t.assetCatalog.AssetsForInstance(inst.Group, env, inst.Name)
-			itemToAssets[service] += len(assets)
-			totalAssets += len(assets)
-		}
+		itemToAssets[service] = t.countAssets(env, svcInfo)
+		totalAssets += itemToAssets[service]
 		logger.WithField("svc", service).WithField("assetsPerSvc", itemToAssets[service]).Debug("done finding assets for service")
 	}
 	logger.WithField("asset", itemToAssets).WithField("numAssets", totalAssets).Info("counted assets")
 
+	// keep the service with the most assets, the assets of the others are re-pointed to it
 	servicesWithAssets := 0
-
-	// check how many of the services are linked to assets
-	for service, assets := range itemToAssets {
-		if assets > 0 {
-			serviceToKeep = service
+	for _, service := range services {
+		if itemToAssets[service] > 0 {
 			servicesWithAssets++
 		}
+		if itemToAssets[service] > itemToAssets[serviceToKeep] {
+			serviceToKeep = service
+		}
 	}
 
 	t.output = append(t.output, sep)
-	// when greater than 2 output that more care needs to be taken
-	if servicesWithAssets == 2 {
-		t.output = append(t.output, "#\tACTION "+actionString+": For the following services more investigation needed as multiple services have assets")
-		t.actionIndex++
-		for _, service := range services {
-			t.output = append(t.output, fmt.Sprintf("#\t\t%v has %v assets", service, itemToAssets[service]))
-		}
-		t.output = append(t.output, sep)
-		t.output = append(t.output, "")
-		return
+	if servicesWithAssets > 1 {
+		t.output = append(t.output, fmt.Sprintf("#\tACTION "+actionString+": For the following services combine all revisions and re-point the assets of %d services to %s and remove others", servicesWithAssets, serviceToKeep))
+	} else {
+		t.output = append(t.output, fmt.Sprintf("#\tACTION "+actionString+": For the following services combine all revisions to %s and remove others", serviceToKeep))
 	}
-
-	// 1 or fewer services with assets
-	t.output = append(t.output, fmt.Sprintf("#\tACTION "+actionString+": For the following services combine all revisions to %s and remove others", serviceToKeep))
-	t.backup = append(t.backup, sep)
-	t.backup = append(t.backup, "#\tACTION "+actionString+": All backups for action")
 	t.actionIndex++
+	act := &Action{
+		ID:       actionString,
+		Env:      env,
+		GroupKey: groupKey,
+		Kept:     serviceToKeep,
+		Removed:  []string{},
+		Assets:   itemToAssets,
+		Hashes:   []HashResult{},
+		Blockers: map[string][]string{},
+		Skipped:  map[string]string{},
+		Steps:    []Step{},
+	}
 
 	logger = logger.WithField("serviceToKeep", serviceToKeep)
 	logger.Info("starting to compare spec hashes")
@@ -212,38 +275,105 @@ func (t *tool) handleGroup(logger *logrus.Entry, env string, services []string)
 	commandOutput := ""
 	logger = logger.WithField("hashData", hashes)
 	backups := []*service.APIServiceInfo{}
+	republish := map[string]struct{}{}
+	deletes := []Step{}
 	for _, service := range services {
 		if service == serviceToKeep {
 			continue
 		}
 
 		logger = logger.WithField("service", service)
-		logger.Debug("comparing hash of revision on instance to hashes in service to keep")
 		svcInfo := t.serviceRegistry.GetAPIServiceInfo(env, service)
-		backups = append(backups, svcInfo)
-		for _, inst := range svcInfo.APIServiceInstances {
+		blockers, err := t.blockers(env, svcInfo)
+		if err != nil {
+			logger.WithError(err).Error("unable to check subscriptions of service")
+			actionOutput += fmt.Sprintf("#\t\t%v subscriptions could not be checked, it is not deleted\n", service)
+			act.Blockers[service] = []string{err.Error()}
+			continue
+		}
+		if len(blockers) > 0 {
+			logger.WithField("blockers", blockers).Info("service is in use")
+			actionOutput += fmt.Sprintf("#\t\t%v is in use and is not deleted, resolve the following first\n", service)
+			for _, blocker := range blockers {
+				actionOutput += fmt.Sprintf("#\t\t\t%v\n", blocker)
+			}
+			act.Blockers[service] = blockers
+			continue
+		}
+
+		logger.Debug("comparing hash of revision on instance to hashes in service to keep")
+		// the steps of the service are only added to the action once every check has passed
+		steps := []Step{}
+		if len(svcInfo.APIServiceInstances) == 0 {
+			act.Skipped[service] = "it has no instances to compare to " + serviceToKeep
+		}
+		deletable := len(svcInfo.APIServiceInstances) > 0
+		// the revision and instance of the kept service that replace each revision and instance of this service
+		merged := &mergeTargets{revisions: map[string]string{}, instances: map[string]string{}}
+		for _, inst := range sortedInstances(svcInfo) {
+			hashRes := HashResult{Service: service, Instance: inst.Name, Revision: inst.Spec.ApiServiceRevision}
 			hash, err := util.GetAgentDetailsValue(inst, "tempHash")
 			if err != nil {
 				actionOutput += fmt.Sprintf("#\t\t%v no hash found, take care with removing\n", service)
+				act.Skipped[service] = fmt.Sprintf("no spec hash found for instance %s", inst.Name)
+				deletable = false
+				hashRes.Result = HashMissing
+				act.Hashes = append(act.Hashes, hashRes)
 				continue
 			}
 			logger = logger.WithField("hash", hash)
+			hashRes.Hash = hash
 
 			logger.Debug("handling instance hash compare")
-			if _, found := hashes[hash]; found {
+			if keptRev, found := hashes[hash]; found {
+				hashRes.Result = HashFound
 				actionOutput += fmt.Sprintf("#\t\t%v can be deleted without any merge as hash exists on %v and it has %v related assets\n", service, serviceToKeep, itemToAssets[service])
-				commandOutput += fmt.Sprintf("axway central delete -s %v apiservice %v\n", env, service)
+				merged.revisions[inst.Spec.ApiServiceRevision] = keptRev.(string)
+				keptInst, err := keptInstance(svcKeepInfo, keptRev.(string))
+				if err != nil {
+					// only a problem when assets use the instance, which is checked when planning the asset steps
+					logger.WithError(err).Debug("no instance of the service to keep replaces the instance")
+				}
+				merged.instances[inst.Name] = keptInst
 			} else {
+				hashRes.Result = HashMerge
 				actionOutput += fmt.Sprintf("#\t\t%v can be deleted after merging revision %v to %v\n", service, inst.Spec.ApiServiceRevision, serviceToKeep)
-				commandOutput += fmt.Sprintf("axway central get -o json -s %v apiservicerevision %v > %v.json\n", env, inst.Spec.ApiServiceRevision, inst.Spec.ApiServiceRevision)
-				commandOutput += fmt.Sprintf("jq '.spec.apiService |= \"%v\"' %v.json > %v-new.json\n", serviceToKeep, inst.Spec.ApiServiceRevision, inst.Spec.ApiServiceRevision)
-				commandOutput += fmt.Sprintf("axway central apply -f %v-new.json\n", inst.Spec.ApiServiceRevision)
-				commandOutput += fmt.Sprintf("%v\n", sep2)
-				commandOutput += fmt.Sprintf("#\tIn environment %v an update to the APIServiceInstance(s) related to %v may be necessary, in order to point to new revision %v\n", env, service, inst.Spec.ApiServiceRevision)
-				commandOutput += fmt.Sprintf("%v\n", sep2)
+				// the instance references the revision by name, so it moves to the kept service with the revision
+				steps = append(steps, Step{Type: StepMoveRevision, Env: env, Service: serviceToKeep, Revision: inst.Spec.ApiServiceRevision})
+				merged.revisions[inst.Spec.ApiServiceRevision] = inst.Spec.ApiServiceRevision
+				merged.instances[inst.Name] = inst.Name
 			}
+			act.Hashes = append(act.Hashes, hashRes)
+		}
+		if !deletable {
+			continue
 		}
+
+		if itemToAssets[service] > 0 {
+			assetSteps, err := t.assetSteps(env, service, serviceToKeep, svcInfo, merged)
+			if err != nil {
+				actionOutput += fmt.Sprintf("#\t\t%v assets can not be re-pointed to %v, take care with removing: %v\n", service, serviceToKeep, err)
+				act.Skipped[service] = fmt.Sprintf("its assets can not be re-pointed to %s: %s", serviceToKeep, err)
+				continue
+			}
+			actionOutput += fmt.Sprintf("#\t\t%v has %v assets that are re-pointed to %v before it is deleted\n", service, itemToAssets[service], serviceToKeep)
+			for _, s := range assetSteps {
+				republish[s.Asset] = struct{}{}
+			}
+			steps = append(steps, assetSteps...)
+		}
+		act.Steps = append(act.Steps, steps...)
+		act.Removed = append(act.Removed, service)
+		backups = append(backups, svcInfo)
+		deletes = append(deletes, Step{Type: StepDeleteService, Env: env, Service: service})
 	}
+	act.Steps = append(act.Steps, republishSteps(env, republish)...)
+	act.Steps = append(act.Steps, deletes...)
+	for _, s := range act.Steps {
+		commandOutput += s.command()
+	}
+	t.actions = append(t.actions, act)
+
 	// append actionOutput to log
 	actionOutput = strings.TrimRight(actionOutput, "\n")
 	t.output = append(t.output, actionOutput)
@@ -256,69 +386,67 @@ func (t *tool) handleGroup(logger *logrus.Entry, env string, services []string)
 	t.output = append(t.output, sep)
 	t.output = append(t.output, "")
 
-	// append backup data to log
+	t.appendBackup(actionString, backups)
+}
+
+// appendBackup adds the services of an action to the backup
+func (t *tool) appendBackup(actionID string, backups []*service.APIServiceInfo) {
+	t.backup = append(t.backup, sep)
+	t.backup = append(t.backup, "#\tACTION "+actionID+": All backups for action")
 	j, _ := json.Marshal(backups)
 	t.backup = append(t.backup, string(j))
 	t.backup = append(t.backup, sep)
 	t.backup = append(t.backup, "")
 }
 
+// countAssets returns the number of assets referencing the instances of the service
+func (t *tool) countAssets(env string, svcInfo *service.APIServiceInfo) int {
+	count := 0
+	for _, inst := range svcInfo.APIServiceInstances {
+		count += len(t.assetCatalog.AssetsForInstance(inst.Group, env, inst.Name))
+	}
+	return count
+}
+
 func (t *tool) groupServicesInEnv(env string) map[string][]string {
-	grouping := make(map[string][]string)
+	serviceKeys := make(map[string][]string)
 	logger := t.logger.WithField("env", env)
 	servicesInfo := t.serviceRegistry.GetAPIServicesInfo(env)
 
-	groupBy := ""
-
 	for service, serviceInfo := range servicesInfo {
-		logger = logger.WithField("svc", service)
-		svcDetails := util.GetAgentDetails(serviceInfo.APIService)
-		hashes := map[string]interface{}{}
-		if v, found := svcDetails["specHashes"]; found {
-			hashes = v.(map[string]interface{})
-		}
-		for _, inst := range serviceInfo.APIServiceInstances {
-			logger = logger.WithField("instance", inst.Name)
-
-			details := util.GetAgentDetailStrings(inst)
-			if groupBy == "" {
-				// use the first service to determine if we will group by api id or primary key
-				if _, found := details[definitions.AttrExternalAPIID]; found {
-					groupBy = definitions.AttrExternalAPIID
-				} else if _, found := details[definitions.AttrExternalAPIPrimaryKey]; found {
-					groupBy = definitions.AttrExternalAPIPrimaryKey
-				} else {
-					logger.Error("can't determine how to group services")
-					break
-				}
-				logger = logger.WithField("groupBy", groupBy)
-			}
+		logger := logger.WithField("svc", service)
+		t.setInstanceHashes(logger, env, service, serviceInfo)
 
-			// get revision hash from service and add to tempHash x-agent-detail on instance for duplication processing
-			rev := inst.Spec.ApiServiceRevision
-			logger.WithField("hashData", hashes).WithField("rev.Name", rev).Debug("looking for revision hash on service")
-			for hash, revName := range hashes {
-				if rev == revName.(string) {
-					util.SetAgentDetailsKey(inst, "tempHash", hash)
-					t.serviceRegistry.UpdateAPIServiceInst(env, service, inst)
-					break
-				}
-			}
+		keys, strategy := t.groupKeys(&serviceInfo)
+		if len(keys) == 0 {
+			logger.Warn("can't find grouping key for service")
+			continue
+		}
+		logger.WithField("groupBy", strategy).Debug("found grouping key for service")
+		serviceKeys[service] = keys
+	}
+	logger.Debug("finished grouping services in environment")
+	return groupByKeys(serviceKeys)
+}
 
-			if key, found := details[groupBy]; found {
-				if _, ok := grouping[key]; !ok {
-					grouping[key] = []string{}
-				}
-				grouping[key] = append(grouping[key], service)
+// setInstanceHashes adds the revision hash from the service to the tempHash x-agent-detail on each instance for duplication processing
+func (t *tool) setInstanceHashes(logger *logrus.Entry, env, svcName string, serviceInfo service.APIServiceInfo) {
+	svcDetails := util.GetAgentDetails(serviceInfo.APIService)
+	hashes := map[string]interface{}{}
+	if v, found := svcDetails["specHashes"]; found {
+		hashes = v.(map[string]interface{})
+	}
+	for _, inst := range serviceInfo.APIServiceInstances {
+		rev := inst.Spec.ApiServiceRevision
+		logger.WithField("instance", inst.Name).WithField("hashData", hashes).WithField("rev.Name", rev).Debug("looking for revision hash on service")
+		for hash, revName := range hashes {
+			if rev == revName.(string) {
+				util.SetAgentDetailsKey(inst, "tempHash", hash)
+				t.serviceRegistry.UpdateAPIServiceInst(env, svcName, inst)
 				break
-			} else {
-				logger.Warn("can't find grouping attribute on service")
 			}
 		}
-		logger.Debug("finished grouping instances in service")
 	}
-	logger.Debug("finished grouping services in environment")
-	return grouping
 }
 
 func (t *tool) Write() error {
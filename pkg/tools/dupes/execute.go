@@ -0,0 +1,96 @@
+package dupes
+
+import (
+	"fmt"
+
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+)
+
+// execute applies the steps of every action through the API, stopping on the first failure
+func (t *tool) execute() error {
+	t.logger.WithField("actions", len(t.actions)).Info("executing duplicate clean up")
+	for _, a := range t.actions {
+		for i, s := range a.Steps {
+			logger := t.logger.
+				WithField("action", a.ID).
+				WithField("step", i).
+				WithField("type", s.Type).
+				WithField("env", s.Env).
+				WithField("service", s.Service).
+				WithField("revision", s.Revision).
+				WithField("instance", s.Instance).
+				WithField("asset", s.Asset)
+			if err := t.executeStep(s); err != nil {
+				logger.WithError(err).Error("step failed, stopping execution")
+				return fmt.Errorf("action %s step %d failed: %s", a.ID, i, err)
+			}
+			logger.Info("step executed")
+		}
+	}
+	t.logger.Info("duplicate clean up finished")
+	return nil
+}
+
+func (t *tool) executeStep(s Step) error {
+	switch s.Type {
+	case StepMoveRevision:
+		rev := management.NewAPIServiceRevision(s.Revision, s.Env)
+		ri, err := t.apicClient.GetResource(rev.GetSelfLink())
+		if err != nil {
+			return err
+		}
+		rev.FromInstance(ri)
+		rev.Spec.ApiService = s.Service
+		_, err = t.apicClient.UpdateResourceInstance(rev)
+		return err
+	case StepRepointAssetResource:
+		ar, _ := catalog.NewAssetResource(s.AssetResource, catalog.AssetGVK().Kind, s.Asset)
+		ri, err := t.apicClient.GetResource(ar.GetSelfLink())
+		if err != nil {
+			return err
+		}
+		ar.FromInstance(ri)
+		ar.References.ApiServiceInstance = resourceRef(s.Env, s.Instance)
+		ar.References.ApiServiceRevision = resourceRef(s.Env, s.Revision)
+		return t.apicClient.CreateSubResource(ri.ResourceMeta, map[string]interface{}{"references": ar.References})
+	case StepRepointAssetMapping:
+		am := catalog.NewAssetMapping(s.AssetMapping, s.Asset)
+		ri, err := t.apicClient.GetResource(am.GetSelfLink())
+		if err != nil {
+			return err
+		}
+		am.FromInstance(ri)
+		am.Spec.Inputs.ApiService = resourceRef(s.Env, s.Service)
+		if s.Revision != "" {
+			am.Spec.Inputs.ApiServiceRevision = resourceRef(s.Env, s.Revision)
+		}
+		_, err = t.apicClient.UpdateResourceInstance(am)
+		return err
+	case StepRepublishAsset:
+		return t.republishAsset(s.Asset)
+	case StepDeleteService:
+		svc := management.NewAPIService(s.Service, s.Env)
+		return t.apicClient.DeleteResourceInstance(svc)
+	}
+	return fmt.Errorf("unknown step type %s", s.Type)
+}
+
+// republishAsset sets the asset to draft and creates a new patch release of it
+func (t *tool) republishAsset(assetName string) error {
+	asset := catalog.NewAsset(assetName)
+	ri, err := t.apicClient.GetResource(asset.GetSelfLink())
+	if err != nil {
+		return err
+	}
+	err = t.apicClient.CreateSubResource(ri.ResourceMeta, map[string]interface{}{"state": catalog.AssetStateDRAFT})
+	if err != nil {
+		return err
+	}
+
+	releaseTag, _ := catalog.NewReleaseTag("", catalog.AssetGVK().Kind, assetName)
+	releaseTag.Spec.ReleaseType = "patch"
+	releaseTag.Title = ri.Title
+	_, err = t.apicClient.CreateResourceInstance(releaseTag)
+	return err
+}
@@ -0,0 +1,264 @@
+package dupes
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	"github.com/Axway/agent-sdk/pkg/apic/definitions"
+	"github.com/Axway/agent-sdk/pkg/util"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+)
+
+const (
+	groupByExternalAPIID = "externalAPIID"
+	groupByPrimaryKey    = "primaryKey"
+	groupByTitle         = "title"
+	groupByEndpoint      = "endpoint"
+	groupBySpecHash      = "specHash"
+
+	// compositeSep joins the strategies of a composite key, e.g. title+endpoint
+	compositeSep = "+"
+)
+
+// fallbackStrategies are tried, per service, when the key of the configured strategy is missing. The endpoint and
+// specHash strategies are not fallbacks, services that only share an endpoint or a spec may be different APIs.
+var fallbackStrategies = []string{
+	groupByExternalAPIID,
+	groupByPrimaryKey,
+}
+
+// idStrategies identify the external API, agents have set either attribute, so their keys are matched across both
+var idStrategies = map[string]bool{
+	groupByExternalAPIID: true,
+	groupByPrimaryKey:    true,
+}
+
+// idKeyPrefix is the prefix of the keys of the id strategies, which share one namespace
+const idKeyPrefix = "id:"
+
+// groupKeyFuncs return the key of a service for a strategy, or an empty string when the service does not have one
+var groupKeyFuncs = map[string]func(*service.APIServiceInfo) string{
+	groupByExternalAPIID: externalAPIIDKey,
+	groupByPrimaryKey:    primaryKeyKey,
+	groupByTitle:         titleKey,
+	groupByEndpoint:      endpointKey,
+	groupBySpecHash:      specHashKey,
+}
+
+// parseGroupBy returns the strategies of a group_by option, an empty option uses the fallback strategies only
+func parseGroupBy(groupBy string) ([]string, error) {
+	strategies := []string{}
+	for _, strategy := range strings.Split(groupBy, compositeSep) {
+		strategy = strings.TrimSpace(strategy)
+		if strategy == "" {
+			continue
+		}
+		if _, found := groupKeyFuncs[strategy]; !found {
+			return nil, fmt.Errorf("unknown group_by strategy %q, expected %s, %s, %s, %s or %s, joined with %s for a composite",
+				strategy, groupByExternalAPIID, groupByPrimaryKey, groupByTitle, groupByEndpoint, groupBySpecHash, compositeSep)
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies, nil
+}
+
+// groupKeys returns the keys to group the service by and the strategy used. The configured strategies are used
+// first, all of them must have a key for a composite, and then the fallback strategies. The id strategies return
+// every external API ID and primary key of the service, a service is grouped with all services sharing any of them.
+func (t *tool) groupKeys(svcInfo *service.APIServiceInfo) ([]string, string) {
+	if len(t.groupBy) > 1 || (len(t.groupBy) == 1 && !idStrategies[t.groupBy[0]]) {
+		if key := compositeKey(svcInfo, t.groupBy); key != "" {
+			strategy := strings.Join(t.groupBy, compositeSep)
+			return []string{strategy + ":" + key}, strategy
+		}
+	}
+
+	strategies := fallbackStrategies
+	if len(t.groupBy) == 1 && idStrategies[t.groupBy[0]] {
+		// try the configured id strategy first
+		strategies = append([]string{t.groupBy[0]}, fallbackStrategies...)
+	}
+	keys := []string{}
+	found := map[string]bool{}
+	strategy := ""
+	for _, s := range strategies {
+		for _, value := range instanceDetails(svcInfo, s) {
+			if found[value] {
+				continue
+			}
+			found[value] = true
+			keys = append(keys, idKeyPrefix+value)
+			if strategy == "" {
+				strategy = s
+			}
+		}
+	}
+	return keys, strategy
+}
+
+// groupByKeys groups the items that share any of their keys, directly or through other items. Each group is
+// returned by the lowest of its keys, so that the group keys do not depend on map order.
+func groupByKeys(itemKeys map[string][]string) map[string][]string {
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(item string) string {
+		if parent[item] == item {
+			return item
+		}
+		parent[item] = find(parent[item])
+		return parent[item]
+	}
+
+	keyItem := map[string]string{}
+	for item, keys := range itemKeys {
+		if _, found := parent[item]; !found {
+			parent[item] = item
+		}
+		for _, key := range keys {
+			other, found := keyItem[key]
+			if !found {
+				keyItem[key] = item
+				continue
+			}
+			parent[find(item)] = find(other)
+		}
+	}
+
+	groupKey := map[string]string{}
+	for key, item := range keyItem {
+		root := find(item)
+		if k, found := groupKey[root]; !found || key < k {
+			groupKey[root] = key
+		}
+	}
+	grouping := map[string][]string{}
+	for item, keys := range itemKeys {
+		if len(keys) == 0 {
+			continue
+		}
+		key := groupKey[find(item)]
+		grouping[key] = append(grouping[key], item)
+	}
+	for _, items := range grouping {
+		sort.Strings(items)
+	}
+	return grouping
+}
+
+func compositeKey(svcInfo *service.APIServiceInfo, strategies []string) string {
+	keys := []string{}
+	for _, strategy := range strategies {
+		key := groupKeyFuncs[strategy](svcInfo)
+		if key == "" {
+			return ""
+		}
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, "|")
+}
+
+// sortedInstances returns the instances of the service in name order, so that the key does not depend on map order
+func sortedInstances(svcInfo *service.APIServiceInfo) []*management.APIServiceInstance {
+	names := make([]string, 0, len(svcInfo.APIServiceInstances))
+	for name := range svcInfo.APIServiceInstances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	instances := make([]*management.APIServiceInstance, 0, len(names))
+	for _, name := range names {
+		instances = append(instances, svcInfo.APIServiceInstances[name])
+	}
+	return instances
+}
+
+// instanceDetail returns the first value of the x-agent-details key found on the instances of the service
+func instanceDetail(svcInfo *service.APIServiceInfo, key string) string {
+	for _, inst := range sortedInstances(svcInfo) {
+		if value := util.GetAgentDetailStrings(inst)[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// idAttributes are the x-agent-details keys of the id strategies
+var idAttributes = map[string]string{
+	groupByExternalAPIID: definitions.AttrExternalAPIID,
+	groupByPrimaryKey:    definitions.AttrExternalAPIPrimaryKey,
+}
+
+// instanceDetails returns every value of the x-agent-details key of the id strategy found on the instances of the service
+func instanceDetails(svcInfo *service.APIServiceInfo, strategy string) []string {
+	values := []string{}
+	for _, inst := range sortedInstances(svcInfo) {
+		if value := util.GetAgentDetailStrings(inst)[idAttributes[strategy]]; value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func externalAPIIDKey(svcInfo *service.APIServiceInfo) string {
+	return instanceDetail(svcInfo, definitions.AttrExternalAPIID)
+}
+
+func primaryKeyKey(svcInfo *service.APIServiceInfo) string {
+	return instanceDetail(svcInfo, definitions.AttrExternalAPIPrimaryKey)
+}
+
+func titleKey(svcInfo *service.APIServiceInfo) string {
+	return strings.ToLower(strings.TrimSpace(svcInfo.APIService.Title))
+}
+
+// endpointKey returns the lower case host and base path, without a trailing slash, of every instance endpoint
+func endpointKey(svcInfo *service.APIServiceInfo) string {
+	endpoints := map[string]struct{}{}
+	for _, inst := range svcInfo.APIServiceInstances {
+		for _, ep := range inst.Spec.Endpoint {
+			host := strings.ToLower(strings.TrimSpace(ep.Host))
+			if host == "" {
+				continue
+			}
+			basePath := strings.TrimRight(strings.TrimSpace(ep.Routing.BasePath), "/")
+			endpoints[host+basePath] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(endpoints))
+	for ep := range endpoints {
+		keys = append(keys, ep)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// revisionByName returns the revision of the service with the name, the revisions are keyed by ID
+func revisionByName(svcInfo *service.APIServiceInfo, name string) *management.APIServiceRevision {
+	for _, rev := range svcInfo.APIServiceRevisions {
+		if rev.Name == name {
+			return rev
+		}
+	}
+	return nil
+}
+
+// specHashKey returns the SHA-256 of the spec of the revision used by the first instance of the service
+func specHashKey(svcInfo *service.APIServiceInfo) string {
+	for _, inst := range sortedInstances(svcInfo) {
+		rev := revisionByName(svcInfo, inst.Spec.ApiServiceRevision)
+		if rev == nil || rev.Spec.Definition.Value == "" {
+			continue
+		}
+		spec, err := base64.StdEncoding.DecodeString(rev.Spec.Definition.Value)
+		if err != nil {
+			spec = []byte(rev.Spec.Definition.Value)
+		}
+		sum := sha256.Sum256(spec)
+		return hex.EncodeToString(sum[:])
+	}
+	return ""
+}
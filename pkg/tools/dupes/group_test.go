@@ -0,0 +1,96 @@
+package dupes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty uses the fallbacks", groupBy: "", want: []string{}},
+		{name: "single strategy", groupBy: "title", want: []string{groupByTitle}},
+		{name: "id strategy", groupBy: "externalAPIID", want: []string{groupByExternalAPIID}},
+		{name: "composite", groupBy: "title+endpoint", want: []string{groupByTitle, groupByEndpoint}},
+		{name: "spaces around the strategies", groupBy: " title + specHash ", want: []string{groupByTitle, groupBySpecHash}},
+		{name: "empty parts are ignored", groupBy: "title++endpoint+", want: []string{groupByTitle, groupByEndpoint}},
+		{name: "unknown strategy", groupBy: "name", wantErr: true},
+		{name: "unknown part of a composite", groupBy: "title+name", wantErr: true},
+		{name: "strategies are case sensitive", groupBy: "Title", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseGroupBy(tc.groupBy)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGroupByKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		itemKeys map[string][]string
+		want     map[string][]string
+	}{
+		{
+			name:     "no items",
+			itemKeys: map[string][]string{},
+			want:     map[string][]string{},
+		},
+		{
+			name:     "distinct keys",
+			itemKeys: map[string][]string{"a": {"id:1"}, "b": {"id:2"}},
+			want:     map[string][]string{"id:1": {"a"}, "id:2": {"b"}},
+		},
+		{
+			name:     "shared key",
+			itemKeys: map[string][]string{"b": {"id:1"}, "a": {"id:1"}},
+			want:     map[string][]string{"id:1": {"a", "b"}},
+		},
+		{
+			name:     "any shared key",
+			itemKeys: map[string][]string{"a": {"id:1", "id:2"}, "b": {"id:3", "id:2"}},
+			want:     map[string][]string{"id:1": {"a", "b"}},
+		},
+		{
+			name:     "shared through another item",
+			itemKeys: map[string][]string{"a": {"id:3"}, "b": {"id:3", "id:2"}, "c": {"id:2"}, "d": {"id:4"}},
+			want:     map[string][]string{"id:2": {"a", "b", "c"}, "id:4": {"d"}},
+		},
+		{
+			name:     "chain joined by the last item",
+			itemKeys: map[string][]string{"a": {"id:1"}, "b": {"id:2"}, "c": {"id:3"}, "d": {"id:1", "id:2", "id:3"}},
+			want:     map[string][]string{"id:1": {"a", "b", "c", "d"}},
+		},
+		{
+			name:     "items without keys are not grouped",
+			itemKeys: map[string][]string{"a": {"id:1"}, "b": {}, "c": nil},
+			want:     map[string][]string{"id:1": {"a"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// the grouping must not depend on map order
+			for i := 0; i < 20; i++ {
+				if got := groupByKeys(tc.itemKeys); !reflect.DeepEqual(got, tc.want) {
+					t.Fatalf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
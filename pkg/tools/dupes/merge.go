@@ -0,0 +1,125 @@
+package dupes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+)
+
+// mergeTargets maps the revisions and instances of a removed service to those of the kept service
+type mergeTargets struct {
+	revisions map[string]string
+	instances map[string]string
+}
+
+// keptInstance returns the instance of the kept service using the revision. Another instance may have a different
+// endpoint, so an error is returned rather than guessing when no instance uses the revision.
+func keptInstance(svcKeepInfo *service.APIServiceInfo, revision string) (string, error) {
+	for _, inst := range sortedInstances(svcKeepInfo) {
+		if inst.Spec.ApiServiceRevision == revision {
+			return inst.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no instance of %s uses revision %s", svcKeepInfo.APIService.Name, revision)
+}
+
+// resourceRef returns a reference, in the group/env/name form, to a resource in the environment
+func resourceRef(env, name string) string {
+	return fmt.Sprintf("%s/%s/%s", management.APIServiceGVK().Group, env, name)
+}
+
+// assetSteps plans re-pointing the asset resources and asset mappings of a removed service to the kept service
+func (t *tool) assetSteps(env, removed, kept string, svcInfo *service.APIServiceInfo, merged *mergeTargets) ([]Step, error) {
+	steps := []Step{}
+	assets := map[string]struct{}{}
+	for _, inst := range sortedInstances(svcInfo) {
+		for _, nameWithScope := range t.assetCatalog.AssetsForInstance(inst.Group, env, inst.Name) {
+			elements := strings.SplitN(nameWithScope, "/", 2)
+			if len(elements) != 2 {
+				continue
+			}
+			targetInst := merged.instances[inst.Name]
+			if targetInst == "" {
+				return nil, fmt.Errorf("no instance of %s uses revision %s, which replaces instance %s", kept, merged.revisions[inst.Spec.ApiServiceRevision], inst.Name)
+			}
+			assets[elements[0]] = struct{}{}
+			steps = append(steps, Step{
+				Type:          StepRepointAssetResource,
+				Env:           env,
+				Service:       kept,
+				Revision:      merged.revisions[inst.Spec.ApiServiceRevision],
+				Instance:      targetInst,
+				Asset:         elements[0],
+				AssetResource: elements[1],
+			})
+		}
+	}
+
+	assetNames := make([]string, 0, len(assets))
+	for name := range assets {
+		assetNames = append(assetNames, name)
+	}
+	sort.Strings(assetNames)
+	for _, assetName := range assetNames {
+		mappings, err := t.assetMappings(assetName, env, removed)
+		if err != nil {
+			return nil, err
+		}
+		for _, am := range mappings {
+			revision := ""
+			if am.Spec.Inputs.ApiServiceRevision != "" {
+				elements := strings.Split(am.Spec.Inputs.ApiServiceRevision, "/")
+				revision = merged.revisions[elements[len(elements)-1]]
+				if revision == "" {
+					return nil, fmt.Errorf("asset mapping %s uses revision %s which is not merged to %s", am.Name, am.Spec.Inputs.ApiServiceRevision, kept)
+				}
+			}
+			steps = append(steps, Step{
+				Type:         StepRepointAssetMapping,
+				Env:          env,
+				Service:      kept,
+				Revision:     revision,
+				Asset:        assetName,
+				AssetMapping: am.Name,
+			})
+		}
+	}
+	return steps, nil
+}
+
+// assetMappings returns the mappings of the asset that use the service
+func (t *tool) assetMappings(assetName, env, svcName string) ([]*catalog.AssetMapping, error) {
+	am := catalog.NewAssetMapping("", assetName)
+	ris, err := t.apicClient.GetAPIV1ResourceInstances(nil, am.GetKindLink())
+	if err != nil {
+		return nil, err
+	}
+	svcRef := fmt.Sprintf("%s/%s", env, svcName)
+	mappings := []*catalog.AssetMapping{}
+	for _, ri := range ris {
+		am := catalog.NewAssetMapping("", assetName)
+		am.FromInstance(ri)
+		if am.Spec.Inputs.ApiService == svcRef || strings.HasSuffix(am.Spec.Inputs.ApiService, "/"+svcRef) {
+			mappings = append(mappings, am)
+		}
+	}
+	return mappings, nil
+}
+
+// republishSteps plans a new release of each asset that had resources re-pointed
+func republishSteps(env string, assets map[string]struct{}) []Step {
+	names := make([]string, 0, len(assets))
+	for name := range assets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	steps := []Step{}
+	for _, name := range names {
+		steps = append(steps, Step{Type: StepRepublishAsset, Env: env, Asset: name})
+	}
+	return steps
+}
@@ -0,0 +1,274 @@
+package dupes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+	"gopkg.in/yaml.v3"
+)
+
+// StepType is the change a step makes
+type StepType string
+
+// The results of comparing the spec hash of an instance of a removed service to the kept service. A merge instance has no
+// match and is merged to the kept service with its revision, a found instance is deleted with the removed service.
+const (
+	HashFound   = "found"
+	HashMissing = "missing"
+	HashMerge   = "merge"
+)
+
+const (
+	// StepMoveRevision re-points a revision of a removed service to the kept service
+	StepMoveRevision StepType = "move-revision"
+	// StepRepointAssetResource points an asset resource at the instance and revision of the kept service
+	StepRepointAssetResource StepType = "repoint-asset-resource"
+	// StepRepointAssetMapping points an asset mapping at the kept service
+	StepRepointAssetMapping StepType = "repoint-asset-mapping"
+	// StepRepublishAsset creates a new release of an asset with re-pointed resources
+	StepRepublishAsset StepType = "republish-asset"
+	// StepDeleteService deletes a redundant service
+	StepDeleteService StepType = "delete-service"
+)
+
+// Step is a single change made to clean up a group of duplicate services
+type Step struct {
+	Type          StepType `json:"type" yaml:"type"`
+	Env           string   `json:"env" yaml:"env"`
+	Service       string   `json:"service,omitempty" yaml:"service,omitempty"`
+	Revision      string   `json:"revision,omitempty" yaml:"revision,omitempty"`
+	Instance      string   `json:"instance,omitempty" yaml:"instance,omitempty"`
+	Asset         string   `json:"asset,omitempty" yaml:"asset,omitempty"`
+	AssetResource string   `json:"assetResource,omitempty" yaml:"assetResource,omitempty"`
+	AssetMapping  string   `json:"assetMapping,omitempty" yaml:"assetMapping,omitempty"`
+}
+
+// HashResult is the comparison of the spec hash of an instance of a removed service to the hashes of the kept service
+type HashResult struct {
+	Service  string `json:"service" yaml:"service"`
+	Instance string `json:"instance" yaml:"instance"`
+	Revision string `json:"revision" yaml:"revision"`
+	Hash     string `json:"hash,omitempty" yaml:"hash,omitempty"`
+	Result   string `json:"result" yaml:"result"`
+}
+
+// Action is the plan to clean up a group of duplicate services, the steps are executed in order.
+// Actions that need investigation have a review message and no steps. Services that are in use are
+// listed in the blockers, with what uses them, and are not removed. The other services that are not removed are
+// listed in skipped, with the reason. Cross environment actions list their
+// services, and the keys of their asset counts, as env/name.
+type Action struct {
+	ID       string              `json:"id" yaml:"id"`
+	Env      string              `json:"env" yaml:"env"`
+	GroupKey string              `json:"groupKey" yaml:"groupKey"`
+	Kept     string              `json:"kept,omitempty" yaml:"kept,omitempty"`
+	Removed  []string            `json:"removed" yaml:"removed"`
+	Assets   map[string]int      `json:"assets" yaml:"assets"`
+	Hashes   []HashResult        `json:"hashes" yaml:"hashes"`
+	Services []string            `json:"services,omitempty" yaml:"services,omitempty"`
+	OwnerEnv string              `json:"ownerEnv,omitempty" yaml:"ownerEnv,omitempty"`
+	Review   string              `json:"review,omitempty" yaml:"review,omitempty"`
+	Blockers map[string][]string `json:"blockers,omitempty" yaml:"blockers,omitempty"`
+	Skipped  map[string]string   `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+	Steps    []Step              `json:"steps" yaml:"steps"`
+}
+
+// Plan is the machine readable form of the actions log, which other tools read to find what a clean up deletes
+type Plan struct {
+	Actions []*Action `json:"actions" yaml:"actions"`
+}
+
+func isYAMLFile(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// writePlan saves the plan as yaml when the file has a yaml extension and json otherwise
+func writePlan(fileName string, p *Plan) error {
+	var buf []byte
+	var err error
+	if isYAMLFile(fileName) {
+		buf, err = yaml.Marshal(p)
+	} else {
+		buf, err = json.MarshalIndent(p, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, buf, 0644)
+}
+
+// ReadPlan reads a plan written by writePlan, possibly edited to hold only the approved actions
+func ReadPlan(fileName string) (*Plan, error) {
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{}
+	if isYAMLFile(fileName) {
+		err = yaml.Unmarshal(buf, p)
+	} else {
+		err = json.Unmarshal(buf, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// plans from earlier versions have update-instance steps, which did not change the instance
+	for _, a := range p.Actions {
+		steps := []Step{}
+		for _, s := range a.Steps {
+			if s.Type != "update-instance" {
+				steps = append(steps, s)
+			}
+		}
+		a.Steps = steps
+	}
+	return p, nil
+}
+
+// command returns the axway central commands that make the same change as the step
+func (s Step) command() string {
+	switch s.Type {
+	case StepMoveRevision:
+		command := fmt.Sprintf("axway central get -o json -s %v apiservicerevision %v > %v.json\n", s.Env, s.Revision, s.Revision)
+		command += fmt.Sprintf("jq '.spec.apiService |= \"%v\"' %v.json > %v-new.json\n", s.Service, s.Revision, s.Revision)
+		command += fmt.Sprintf("axway central apply -f %v-new.json\n", s.Revision)
+		return command
+	case StepRepointAssetResource:
+		command := fmt.Sprintf("axway central get -o json -s %v assetresource %v > %v.json\n", s.Asset, s.AssetResource, s.AssetResource)
+		command += fmt.Sprintf("jq '.references.apiServiceInstance |= \"%v\" | .references.apiServiceRevision |= \"%v\" | {references: .references}' %v.json > %v-references.json\n",
+			resourceRef(s.Env, s.Instance), resourceRef(s.Env, s.Revision), s.AssetResource, s.AssetResource)
+		command += subResourceCommand(s.change().selfLink, "references", s.AssetResource+"-references.json")
+		return command
+	case StepRepointAssetMapping:
+		filter := fmt.Sprintf(".spec.inputs.apiService |= \"%v\"", resourceRef(s.Env, s.Service))
+		if s.Revision != "" {
+			filter += fmt.Sprintf(" | .spec.inputs.apiServiceRevision |= \"%v\"", resourceRef(s.Env, s.Revision))
+		}
+		command := fmt.Sprintf("axway central get -o json -s %v assetmapping %v > %v.json\n", s.Asset, s.AssetMapping, s.AssetMapping)
+		command += fmt.Sprintf("jq '%v' %v.json > %v-new.json\n", filter, s.AssetMapping, s.AssetMapping)
+		command += fmt.Sprintf("axway central apply -f %v-new.json\n", s.AssetMapping)
+		return command
+	case StepRepublishAsset:
+		command := fmt.Sprintf("%v\n", sep2)
+		command += fmt.Sprintf("#\tRepublish asset %v, set it to draft and create a new patch release, so that its release uses the re-pointed resources\n", s.Asset)
+		command += fmt.Sprintf("%v\n", sep2)
+		command += fmt.Sprintf("axway central get -o json asset %v > %v.json\n", s.Asset, s.Asset)
+		command += fmt.Sprintf("echo '{\"state\": \"draft\"}' > %v-state.json\n", s.Asset)
+		command += subResourceCommand(s.change().selfLink, "state", s.Asset+"-state.json")
+		command += fmt.Sprintf("jq '{group: \"catalog\", apiVersion: \"v1alpha1\", kind: \"ReleaseTag\", title: .title, metadata: {scope: {kind: \"Asset\", name: .name}}, spec: {releaseType: \"patch\"}}' %v.json > %v-release.json\n", s.Asset, s.Asset)
+		command += fmt.Sprintf("axway central create -f %v-release.json\n", s.Asset)
+		return command
+	case StepDeleteService:
+		return fmt.Sprintf("axway central delete -s %v apiservice %v\n", s.Env, s.Service)
+	}
+	return ""
+}
+
+// subResourceCommand returns the command that updates the sub resource of a resource from the file, as apply does not
+// update sub resources. It uses the CENTRAL_URL, TENANT_ID and ACCESS_TOKEN variables, which the clean up scripts set.
+func subResourceCommand(selfLink, subResource, fileName string) string {
+	return fmt.Sprintf("curl -sS -f -X PUT -H \"Authorization: Bearer $ACCESS_TOKEN\" -H \"X-Axway-Tenant-Id: $TENANT_ID\" -H \"Content-Type: application/json\" --data-binary @%v \"$CENTRAL_URL/apis%v/%v\"\n",
+		fileName, selfLink, subResource)
+}
+
+// runPlan follows the actions of a plan file, e.g. the approved subset of a previous plan, rather than finding duplicates
+func (t *tool) runPlan() error {
+	logger := t.logger.WithField("planFile", t.cfg.FromPlan)
+	p, err := ReadPlan(t.cfg.FromPlan)
+	if err != nil {
+		logger.WithError(err).Error("could not read plan file: stopping the tool")
+		return err
+	}
+	logger.WithField("actions", len(p.Actions)).Info("following plan")
+
+	// every action is checked against the current state before anything is written or executed
+	actionBackups := map[string][]*service.APIServiceInfo{}
+	problems := []string{}
+	for _, a := range p.Actions {
+		backups, actionProblems := t.checkPlanAction(a)
+		actionBackups[a.ID] = backups
+		problems = append(problems, actionProblems...)
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			logger.Error(problem)
+		}
+		err := fmt.Errorf("%d problems found in the plan, nothing was executed", len(problems))
+		logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+
+	for _, a := range p.Actions {
+		if len(a.Steps) == 0 {
+			logger.WithField("action", a.ID).Info("skipping action with no steps")
+			continue
+		}
+		backups := actionBackups[a.ID]
+
+		commandOutput := ""
+		for _, s := range a.Steps {
+			commandOutput += s.command()
+		}
+		t.output = append(t.output, sep)
+		t.output = append(t.output, fmt.Sprintf("#\tACTION %s: For the following services combine all revisions to %s and remove others", a.ID, a.Kept))
+		t.output = append(t.output, "#\tExecute the following commands to clean these duplicated services")
+		t.output = append(t.output, strings.TrimRight(commandOutput, "\n"))
+		t.output = append(t.output, sep)
+		t.output = append(t.output, "")
+		t.appendBackup(a.ID, backups)
+		t.actions = append(t.actions, a)
+	}
+	return t.writeOutput()
+}
+
+// checkPlanAction checks the steps of a plan action against the services read now. Each service the action deletes
+// must exist in the environments read, so that it is backed up, and must still not be in use. The services that
+// revisions and asset mappings are moved to must exist and not be deleted. The backups of the deleted services are
+// returned with a description of each problem found.
+func (t *tool) checkPlanAction(a *Action) ([]*service.APIServiceInfo, []string) {
+	backups := []*service.APIServiceInfo{}
+	problems := []string{}
+	deleted := map[string]struct{}{}
+	for _, s := range a.Steps {
+		if s.Type != StepDeleteService {
+			continue
+		}
+		name := s.Env + "/" + s.Service
+		deleted[name] = struct{}{}
+		svcInfo := t.serviceRegistry.GetAPIServiceInfo(s.Env, s.Service)
+		if svcInfo == nil {
+			problems = append(problems, fmt.Sprintf("action %s: service %s to delete was not found in the environments read, it can not be backed up", a.ID, name))
+			continue
+		}
+		blockers, err := t.blockers(s.Env, svcInfo)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("action %s: subscriptions of service %s could not be checked: %s", a.ID, name, err))
+			continue
+		}
+		for _, blocker := range blockers {
+			problems = append(problems, fmt.Sprintf("action %s: service %s is in use, %s", a.ID, name, blocker))
+		}
+		backups = append(backups, svcInfo)
+	}
+
+	for _, s := range a.Steps {
+		if s.Type != StepMoveRevision && s.Type != StepRepointAssetMapping {
+			continue
+		}
+		name := s.Env + "/" + s.Service
+		if _, found := deleted[name]; found {
+			problems = append(problems, fmt.Sprintf("action %s: %s step moves to service %s, which the action deletes", a.ID, s.Type, name))
+			continue
+		}
+		if t.serviceRegistry.GetAPIServiceInfo(s.Env, s.Service) == nil {
+			problems = append(problems, fmt.Sprintf("action %s: %s step moves to service %s, which was not found", a.ID, s.Type, name))
+		}
+	}
+	return backups, problems
+}
@@ -0,0 +1,272 @@
+package dupes
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reportService is a service of a duplicate group as shown in the html report
+type reportService struct {
+	Name           string
+	Env            string
+	Created        string
+	Instances      int
+	Assets         int
+	SpecHashes     string
+	Recommendation string
+	Kept           bool
+}
+
+// reportAction is a duplicate group as shown in the html report
+type reportAction struct {
+	ID             string
+	GroupKey       string
+	Recommendation string
+	Steps          int
+	Services       []reportService
+}
+
+// reportEnv is the duplicate groups of an environment, or of several environments, as shown in the html report
+type reportEnv struct {
+	Name    string
+	Actions []reportAction
+}
+
+type report struct {
+	Generated string
+	Actions   int
+	Services  int
+	Deletes   int
+	Envs      []reportEnv
+}
+
+// writeHTMLReport saves the actions as a standalone html report, with no external assets
+func (t *tool) writeHTMLReport() error {
+	t.logger.WithField("htmlReport", t.cfg.HTMLReport).Info("writing html report")
+	file, err := os.Create(t.cfg.HTMLReport)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return reportTemplate.Execute(file, t.buildReport())
+}
+
+func (t *tool) buildReport() *report {
+	r := &report{Generated: time.Now().UTC().Format(time.RFC3339)}
+	envs := map[string]*reportEnv{}
+	crossEnv := &reportEnv{Name: "Across environments"}
+	for _, a := range t.actions {
+		ra := t.reportAction(a)
+		r.Actions++
+		r.Services += len(ra.Services)
+		for _, s := range a.Steps {
+			if s.Type == StepDeleteService {
+				r.Deletes++
+			}
+		}
+		if len(a.Services) > 0 {
+			crossEnv.Actions = append(crossEnv.Actions, ra)
+			continue
+		}
+		if _, found := envs[a.Env]; !found {
+			envs[a.Env] = &reportEnv{Name: a.Env}
+		}
+		envs[a.Env].Actions = append(envs[a.Env].Actions, ra)
+	}
+
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r.Envs = append(r.Envs, *envs[name])
+	}
+	if len(crossEnv.Actions) > 0 {
+		r.Envs = append(r.Envs, *crossEnv)
+	}
+	return r
+}
+
+// reportAction returns the services of the action, with the recommendation for each
+func (t *tool) reportAction(a *Action) reportAction {
+	ra := reportAction{ID: a.ID, GroupKey: a.GroupKey, Steps: len(a.Steps)}
+	deleted := map[string]struct{}{}
+	for _, s := range a.Steps {
+		if s.Type == StepDeleteService {
+			deleted[s.Service] = struct{}{}
+		}
+	}
+
+	switch {
+	case a.Review != "":
+		ra.Recommendation = a.Review
+	case len(deleted) > 0:
+		ra.Recommendation = fmt.Sprintf("Keep %s and delete %d services", a.Kept, len(deleted))
+	default:
+		ra.Recommendation = fmt.Sprintf("Keep %s, no services can be deleted safely", a.Kept)
+	}
+
+	// cross environment actions list their services as env/name, the others are in the environment of the action.
+	// Every member of the group has an asset count, services without one are taken from the other lists of the action.
+	services := a.Services
+	if len(services) == 0 {
+		members := map[string]struct{}{}
+		for _, name := range a.Removed {
+			members[name] = struct{}{}
+		}
+		for name := range a.Assets {
+			members[name] = struct{}{}
+		}
+		for _, h := range a.Hashes {
+			members[h.Service] = struct{}{}
+		}
+		for name := range a.Blockers {
+			members[name] = struct{}{}
+		}
+		for name := range a.Skipped {
+			members[name] = struct{}{}
+		}
+		delete(members, a.Kept)
+		names := make([]string, 0, len(members))
+		for name := range members {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range append([]string{a.Kept}, names...) {
+			services = append(services, a.Env+"/"+name)
+		}
+	}
+
+	seen := map[string]struct{}{}
+	for _, envService := range services {
+		if _, found := seen[envService]; found {
+			continue
+		}
+		seen[envService] = struct{}{}
+		elements := strings.SplitN(envService, "/", 2)
+		rs := reportService{Env: elements[0], Name: elements[1], SpecHashes: specHashResults(a, elements[1])}
+		if len(a.Services) > 0 {
+			rs.Assets = a.Assets[envService]
+			rs.Recommendation = "Review"
+			rs.Kept = rs.Env == a.OwnerEnv
+		} else {
+			rs.Assets = a.Assets[rs.Name]
+			rs.Kept = rs.Name == a.Kept
+			rs.Recommendation = serviceRecommendation(a, rs.Name, deleted)
+		}
+		if svcInfo := t.serviceRegistry.GetAPIServiceInfo(rs.Env, rs.Name); svcInfo != nil {
+			rs.Instances = len(svcInfo.APIServiceInstances)
+			rs.Created = time.Time(svcInfo.APIService.Metadata.Audit.CreateTimestamp).UTC().Format(time.RFC3339)
+		}
+		ra.Services = append(ra.Services, rs)
+	}
+	return ra
+}
+
+// specHashResults summarizes the spec hash comparison of the instances of a service to the kept service
+func specHashResults(a *Action, service string) string {
+	counts := map[string]int{}
+	for _, h := range a.Hashes {
+		if h.Service == service {
+			counts[h.Result]++
+		}
+	}
+	results := []string{}
+	for _, result := range []string{HashFound, HashMerge, HashMissing} {
+		if counts[result] > 0 {
+			results = append(results, fmt.Sprintf("%d %s", counts[result], result))
+		}
+	}
+	return strings.Join(results, ", ")
+}
+
+func serviceRecommendation(a *Action, service string, deleted map[string]struct{}) string {
+	if service == a.Kept {
+		return "Keep"
+	}
+	if blockers, found := a.Blockers[service]; found {
+		return "In use, not deleted: " + strings.Join(blockers, "; ")
+	}
+	if reason, found := a.Skipped[service]; found {
+		return "Not deleted, " + reason
+	}
+	if _, found := deleted[service]; !found {
+		return "Not deleted, review the actions log"
+	}
+	for _, h := range a.Hashes {
+		if h.Service == service && h.Result == HashMerge {
+			return "Merge to " + a.Kept + " and delete"
+		}
+	}
+	if a.Assets[service] > 0 {
+		return "Re-point assets to " + a.Kept + " and delete"
+	}
+	return "Delete"
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Duplicate Services Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+h2 { border-bottom: 2px solid #ccc; padding-bottom: 0.2em; margin-top: 2em; }
+h3 { margin-bottom: 0.3em; }
+.summary { color: #555; }
+.recommendation { margin: 0.3em 0 0.6em 0; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ddd; padding: 0.4em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; white-space: nowrap; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.number { text-align: right; }
+tr.kept { background: #e3f4e1; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Duplicate Services Report</h1>
+<p class="summary">Generated {{.Generated}}: {{.Actions}} duplicate groups, {{.Services}} services, {{.Deletes}} services recommended for deletion. The kept service of each group is highlighted. Click a column header to sort.</p>
+{{range .Envs}}
+<h2>{{.Name}}</h2>
+{{range .Actions}}
+<h3>Action {{.ID}}: {{.GroupKey}}</h3>
+<p class="recommendation">{{.Recommendation}} ({{.Steps}} steps)</p>
+<table class="sortable">
+<thead><tr><th>Service</th><th>Environment</th><th>Created</th><th>Instances</th><th>Assets</th><th>Spec hashes</th><th>Recommended action</th></tr></thead>
+<tbody>
+{{range .Services}}<tr{{if .Kept}} class="kept"{{end}}><td>{{.Name}}</td><td>{{.Env}}</td><td>{{.Created}}</td><td class="number">{{.Instances}}</td><td class="number">{{.Assets}}</td><td>{{.SpecHashes}}</td><td>{{.Recommendation}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{else}}
+<p>No duplicate services found.</p>
+{{end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var asc = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[column].textContent, y = b.cells[column].textContent;
+        var result = a.cells[column].classList.contains("number") ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+        return asc ? result : -result;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,482 @@
+package dupes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+)
+
+const (
+	scriptBash       = "bash"
+	scriptPowerShell = "powershell"
+)
+
+// scriptFiles are the default script file names for each script format
+var scriptFiles = map[string]string{
+	scriptBash:       "duplicate-cleanup.sh",
+	scriptPowerShell: "duplicate-cleanup.ps1",
+}
+
+// scriptField is a value set on a resource, by its json path, before it is applied
+type scriptField struct {
+	path  []string
+	value string
+}
+
+// scriptChange is a step as the change made to a single resource, which is either updated, deleted or republished.
+// A change to a sub resource, e.g. the references of an asset resource, is sent to the self link of the resource,
+// as execute does, since apply does not update sub resources.
+type scriptChange struct {
+	kind        string
+	scope       string
+	name        string
+	selfLink    string
+	subResource string
+	fields      []scriptField
+	delete      bool
+	republish   bool
+}
+
+// change returns the change to a single resource that the step makes
+func (s Step) change() scriptChange {
+	switch s.Type {
+	case StepMoveRevision:
+		return scriptChange{kind: "apiservicerevision", scope: s.Env, name: s.Revision, fields: []scriptField{
+			{path: []string{"spec", "apiService"}, value: s.Service},
+		}}
+	case StepRepointAssetResource:
+		ar, _ := catalog.NewAssetResource(s.AssetResource, catalog.AssetGVK().Kind, s.Asset)
+		return scriptChange{kind: "assetresource", scope: s.Asset, name: s.AssetResource, selfLink: ar.GetSelfLink(), subResource: "references", fields: []scriptField{
+			{path: []string{"references", "apiServiceInstance"}, value: resourceRef(s.Env, s.Instance)},
+			{path: []string{"references", "apiServiceRevision"}, value: resourceRef(s.Env, s.Revision)},
+		}}
+	case StepRepointAssetMapping:
+		fields := []scriptField{{path: []string{"spec", "inputs", "apiService"}, value: resourceRef(s.Env, s.Service)}}
+		if s.Revision != "" {
+			fields = append(fields, scriptField{path: []string{"spec", "inputs", "apiServiceRevision"}, value: resourceRef(s.Env, s.Revision)})
+		}
+		return scriptChange{kind: "assetmapping", scope: s.Asset, name: s.AssetMapping, fields: fields}
+	case StepRepublishAsset:
+		return scriptChange{kind: "asset", name: s.Asset, selfLink: catalog.NewAsset(s.Asset).GetSelfLink(), republish: true}
+	case StepDeleteService:
+		return scriptChange{kind: "apiservice", scope: s.Env, name: s.Service, delete: true}
+	}
+	return scriptChange{}
+}
+
+// writeScript saves the actions with steps as a runnable script in the script format
+func (t *tool) writeScript() error {
+	scriptFile := t.cfg.ScriptFile
+	if scriptFile == "" {
+		scriptFile = scriptFiles[t.cfg.ScriptFormat]
+	}
+
+	var script string
+	switch t.cfg.ScriptFormat {
+	case scriptBash:
+		script = bashScript(t.actions, t.cfg.URL, t.cfg.OrgID)
+	case scriptPowerShell:
+		script = powerShellScript(t.actions, t.cfg.URL, t.cfg.OrgID)
+	}
+	t.logger.WithField("scriptFile", scriptFile).WithField("format", t.cfg.ScriptFormat).Info("writing clean up script")
+	return os.WriteFile(scriptFile, []byte(script), 0755)
+}
+
+// actionDescription is the summary of an action that is logged when it runs
+func actionDescription(a *Action) string {
+	return fmt.Sprintf("ACTION %s: combine the duplicates of %s in %s to %s, removing %s", a.ID, a.GroupKey, a.Env, a.Kept, strings.Join(a.Removed, ", "))
+}
+
+// quoteString returns the value as a json string, which is also a valid jq string
+func quoteString(value string) string {
+	buf, _ := json.Marshal(value)
+	return string(buf)
+}
+
+const bashPreamble = `#!/usr/bin/env bash
+# Duplicate service clean up generated by amplify-tool duplicate
+#
+# Usage: %[1]s [-y|--yes] [ACTION_ID...]
+#   -y, --yes    run without the confirmation prompt
+#   ACTION_ID    the actions to run, all actions when not set
+#
+# Requires the axway CLI, logged in to the organization, curl and jq. Set LOG_FILE to change the log file.
+# The state and references sub resources are updated with the Amplify API, set CENTRAL_URL and TENANT_ID to change
+# where, and ACCESS_TOKEN to use a token other than the one of the default axway CLI account.
+set -eo pipefail
+
+LOG_FILE="${LOG_FILE:-duplicate-cleanup.log}"
+WORK_DIR="$(mktemp -d)"
+CENTRAL_URL="${CENTRAL_URL:-%[2]s}"
+TENANT_ID="${TENANT_ID:-%[3]s}"
+
+log() {
+  echo "$(date -u +%%Y-%%m-%%dT%%H:%%M:%%SZ) $*" | tee -a "$LOG_FILE" >&2
+}
+
+fail() {
+  log "ERROR: $*"
+  log "stopping, the work files are in $WORK_DIR"
+  exit 1
+}
+
+central() {
+  log "running: axway central $*"
+  axway central "$@" 2>>"$LOG_FILE" || fail "axway central $1 failed"
+}
+
+get_resource() {
+  local kind="$1" scope="$2" name="$3" file="$4"
+  if [ -n "$scope" ]; then
+    central get -o json -s "$scope" "$kind" "$name" >"$file"
+  else
+    central get -o json "$kind" "$name" >"$file"
+  fi
+}
+
+update_resource() {
+  local kind="$1" scope="$2" name="$3" filter="$4"
+  get_resource "$kind" "$scope" "$name" "$WORK_DIR/$name.json"
+  jq "$filter" "$WORK_DIR/$name.json" >"$WORK_DIR/$name-new.json" || fail "unable to update $kind $name"
+  central apply -f "$WORK_DIR/$name-new.json" >>"$LOG_FILE"
+}
+
+access_token() {
+  if [ -z "$ACCESS_TOKEN" ]; then
+    ACCESS_TOKEN="$(axway auth list --json | jq -r '(map(select(.default)) + .)[0].auth.tokens.access_token // empty')" ||
+      fail "unable to read the access token of the axway CLI"
+  fi
+  [ -n "$ACCESS_TOKEN" ] || fail "no access token, log in with axway auth login or set ACCESS_TOKEN"
+}
+
+put_subresource() {
+  local self_link="$1" sub="$2" file="$3"
+  access_token
+  log "running: PUT $CENTRAL_URL/apis$self_link/$sub"
+  curl -sS -f -X PUT -H "Authorization: Bearer $ACCESS_TOKEN" -H "X-Axway-Tenant-Id: $TENANT_ID" \
+    -H "Content-Type: application/json" --data-binary "@$file" "$CENTRAL_URL/apis$self_link/$sub" >>"$LOG_FILE" 2>&1 ||
+    fail "unable to update the $sub of $self_link"
+}
+
+update_subresource() {
+  local kind="$1" scope="$2" name="$3" self_link="$4" sub="$5" filter="$6"
+  get_resource "$kind" "$scope" "$name" "$WORK_DIR/$name.json"
+  jq "$filter | {$sub: .$sub}" "$WORK_DIR/$name.json" >"$WORK_DIR/$name-$sub.json" || fail "unable to update $kind $name"
+  put_subresource "$self_link" "$sub" "$WORK_DIR/$name-$sub.json"
+}
+
+delete_resource() {
+  local kind="$1" scope="$2" name="$3"
+  central delete -s "$scope" "$kind" "$name" >>"$LOG_FILE"
+}
+
+republish_asset() {
+  local name="$1" self_link="$2"
+  get_resource asset "" "$name" "$WORK_DIR/$name.json"
+  echo '{"state": "draft"}' >"$WORK_DIR/$name-state.json"
+  put_subresource "$self_link" state "$WORK_DIR/$name-state.json"
+  jq '{group: "catalog", apiVersion: "v1alpha1", kind: "ReleaseTag", title: .title, metadata: {scope: {kind: "Asset", name: .name}}, spec: {releaseType: "patch"}}' \
+    "$WORK_DIR/$name.json" >"$WORK_DIR/$name-release.json" || fail "unable to create release tag for asset $name"
+  central create -f "$WORK_DIR/$name-release.json" >>"$LOG_FILE"
+}
+`
+
+const bashMain = `
+ASSUME_YES=false
+ACTIONS=()
+for arg in "$@"; do
+  case "$arg" in
+    -y | --yes) ASSUME_YES=true ;;
+    *) ACTIONS+=("$arg") ;;
+  esac
+done
+if [ ${#ACTIONS[@]} -eq 0 ]; then
+  ACTIONS=(%[1]s)
+fi
+if [ ${#ACTIONS[@]} -eq 0 ]; then
+  log "no actions to run"
+  exit 0
+fi
+
+echo "The following actions change and delete resources in Amplify: ${ACTIONS[*]}"
+if [ "$ASSUME_YES" != "true" ]; then
+  read -r -p "Continue? [y/N] " answer
+  case "$answer" in
+    y | Y | yes | YES) ;;
+    *)
+      log "cancelled"
+      exit 1
+      ;;
+  esac
+fi
+
+for id in "${ACTIONS[@]}"; do
+  declare -F "action_$id" >/dev/null || fail "unknown action $id"
+  "action_$id"
+done
+log "duplicate clean up finished"
+rm -rf "$WORK_DIR"
+`
+
+// jqFilter returns the jq filter that sets the fields of the change
+func (c scriptChange) jqFilter() string {
+	filters := []string{}
+	for _, f := range c.fields {
+		filters = append(filters, fmt.Sprintf(".%s |= %s", strings.Join(f.path, "."), quoteString(f.value)))
+	}
+	if len(filters) == 0 {
+		return "."
+	}
+	return strings.Join(filters, " | ")
+}
+
+// bashScript renders the actions as a bash script with a function per action, the sub resources are updated
+// in the central url and tenant when the script does not set others
+func bashScript(actions []*Action, centralURL, tenantID string) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, bashPreamble, scriptFiles[scriptBash], centralURL, tenantID)
+
+	ids := []string{}
+	for _, a := range actions {
+		if len(a.Steps) == 0 {
+			fmt.Fprintf(b, "\n# ACTION %s has no steps, review it in the actions log\n", a.ID)
+			continue
+		}
+		ids = append(ids, a.ID)
+		fmt.Fprintf(b, "\naction_%s() {\n", a.ID)
+		fmt.Fprintf(b, "  log %s\n", quoteString(actionDescription(a)))
+		for _, s := range a.Steps {
+			c := s.change()
+			switch {
+			case c.republish:
+				fmt.Fprintf(b, "  republish_asset %s %s\n", quoteString(c.name), quoteString(c.selfLink))
+			case c.delete:
+				fmt.Fprintf(b, "  delete_resource %s %s %s\n", c.kind, quoteString(c.scope), quoteString(c.name))
+			case c.subResource != "":
+				fmt.Fprintf(b, "  update_subresource %s %s %s %s %s '%s'\n", c.kind, quoteString(c.scope), quoteString(c.name),
+					quoteString(c.selfLink), c.subResource, c.jqFilter())
+			default:
+				fmt.Fprintf(b, "  update_resource %s %s %s '%s'\n", c.kind, quoteString(c.scope), quoteString(c.name), c.jqFilter())
+			}
+		}
+		fmt.Fprintf(b, "  log %s\n", quoteString(fmt.Sprintf("ACTION %s finished", a.ID)))
+		b.WriteString("}\n")
+	}
+
+	fmt.Fprintf(b, bashMain, strings.Join(ids, " "))
+	return b.String()
+}
+
+const powerShellPreamble = `# Duplicate service clean up generated by amplify-tool duplicate
+#
+# Usage: .\%[1]s [-Yes] [-Actions '0001','0002']
+#   -Yes        run without the confirmation prompt
+#   -Actions    the actions to run, all actions when not set
+#
+# Requires the axway CLI, logged in to the organization. Set LOG_FILE to change the log file.
+# The state and references sub resources are updated with the Amplify API, set CENTRAL_URL and TENANT_ID to change
+# where, and ACCESS_TOKEN to use a token other than the one of the default axway CLI account.
+param(
+    [switch]$Yes,
+    [string[]]$Actions = @()
+)
+
+$ErrorActionPreference = "Stop"
+$LogFile = if ($env:LOG_FILE) { $env:LOG_FILE } else { "duplicate-cleanup.log" }
+$WorkDir = Join-Path ([System.IO.Path]::GetTempPath()) ("duplicate-cleanup-" + [guid]::NewGuid())
+New-Item -ItemType Directory -Path $WorkDir | Out-Null
+$CentralUrl = if ($env:CENTRAL_URL) { $env:CENTRAL_URL } else { %[2]s }
+$TenantId = if ($env:TENANT_ID) { $env:TENANT_ID } else { %[3]s }
+$AccessToken = $env:ACCESS_TOKEN
+
+function Write-Log([string]$Message) {
+    $line = "{0} {1}" -f (Get-Date).ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ"), $Message
+    Write-Host $line
+    Add-Content -Path $LogFile -Value $line
+}
+
+function Invoke-Central([string[]]$Arguments) {
+    Write-Log ("running: axway central " + ($Arguments -join " "))
+    $output = & axway central @Arguments
+    if ($LASTEXITCODE -ne 0) {
+        throw ("axway central {0} failed with exit code {1}" -f $Arguments[0], $LASTEXITCODE)
+    }
+    return $output
+}
+
+function Get-Resource([string]$Kind, [string]$Scope, [string]$Name) {
+    $arguments = @("get", "-o", "json")
+    if ($Scope) {
+        $arguments += @("-s", $Scope)
+    }
+    $arguments += @($Kind, $Name)
+    $json = Invoke-Central $arguments
+    return ($json -join [Environment]::NewLine) | ConvertFrom-Json
+}
+
+function Set-ResourceField($Resource, [string[]]$Path, [string]$Value) {
+    $obj = $Resource
+    for ($i = 0; $i -lt $Path.Length - 1; $i++) {
+        if ($null -eq $obj.($Path[$i])) {
+            $obj | Add-Member -NotePropertyName $Path[$i] -NotePropertyValue ([pscustomobject]@{}) -Force
+        }
+        $obj = $obj.($Path[$i])
+    }
+    $obj | Add-Member -NotePropertyName $Path[-1] -NotePropertyValue $Value -Force
+}
+
+function Save-Resource($Resource, [string]$FileName, [string]$Command = "apply") {
+    $file = Join-Path $WorkDir $FileName
+    # write without a byte order mark, which the axway CLI does not read
+    [System.IO.File]::WriteAllText($file, ($Resource | ConvertTo-Json -Depth 100))
+    Invoke-Central @($Command, "-f", $file) | Add-Content -Path $LogFile
+}
+
+function Update-Resource([string]$Kind, [string]$Scope, [string]$Name, [hashtable[]]$Fields = @()) {
+    $resource = Get-Resource $Kind $Scope $Name
+    foreach ($field in $Fields) {
+        Set-ResourceField $resource $field.Path $field.Value
+    }
+    Save-Resource $resource "$Name.json"
+}
+
+function Get-AccessToken {
+    if (-not $script:AccessToken) {
+        $accounts = @((& axway auth list --json) -join [Environment]::NewLine | ConvertFrom-Json)
+        $account = @($accounts | Where-Object { $_.default }) + $accounts | Select-Object -First 1
+        $script:AccessToken = $account.auth.tokens.access_token
+    }
+    if (-not $script:AccessToken) {
+        throw "no access token, log in with axway auth login or set ACCESS_TOKEN"
+    }
+    return $script:AccessToken
+}
+
+function Set-SubResource([string]$SelfLink, [string]$SubResource, $Value) {
+    $uri = "$CentralUrl/apis$SelfLink/$SubResource"
+    Write-Log "running: PUT $uri"
+    $headers = @{ Authorization = "Bearer " + (Get-AccessToken); "X-Axway-Tenant-Id" = $TenantId }
+    $body = @{ $SubResource = $Value } | ConvertTo-Json -Depth 100
+    Invoke-RestMethod -Method Put -Uri $uri -Headers $headers -ContentType "application/json" -Body $body |
+        ConvertTo-Json -Depth 100 | Add-Content -Path $LogFile
+}
+
+function Update-SubResource([string]$Kind, [string]$Scope, [string]$Name, [string]$SelfLink, [string]$SubResource, [hashtable[]]$Fields = @()) {
+    $resource = Get-Resource $Kind $Scope $Name
+    foreach ($field in $Fields) {
+        Set-ResourceField $resource $field.Path $field.Value
+    }
+    Set-SubResource $SelfLink $SubResource $resource.$SubResource
+}
+
+function Remove-Resource([string]$Kind, [string]$Scope, [string]$Name) {
+    Invoke-Central @("delete", "-s", $Scope, $Kind, $Name) | Add-Content -Path $LogFile
+}
+
+function Publish-Asset([string]$Name, [string]$SelfLink) {
+    $asset = Get-Resource "asset" "" $Name
+    Set-SubResource $SelfLink "state" "draft"
+    $releaseTag = [pscustomobject]@{
+        group      = "catalog"
+        apiVersion = "v1alpha1"
+        kind       = "ReleaseTag"
+        title      = $asset.title
+        metadata   = @{ scope = @{ kind = "Asset"; name = $Name } }
+        spec       = @{ releaseType = "patch" }
+    }
+    Save-Resource $releaseTag "$Name-release.json" "create"
+}
+`
+
+const powerShellMain = `
+$AllActions = @(%[1]s)
+if ($Actions.Count -eq 0) {
+    $Actions = $AllActions
+}
+if ($Actions.Count -eq 0) {
+    Write-Log "no actions to run"
+    exit 0
+}
+
+Write-Host ("The following actions change and delete resources in Amplify: " + ($Actions -join " "))
+if (-not $Yes) {
+    $answer = Read-Host "Continue? [y/N]"
+    if ($answer -notmatch "^(y|yes)$") {
+        Write-Log "cancelled"
+        exit 1
+    }
+}
+
+try {
+    foreach ($id in $Actions) {
+        $command = Get-Command "Invoke-Action$id" -CommandType Function -ErrorAction SilentlyContinue
+        if ($null -eq $command) {
+            throw "unknown action $id"
+        }
+        & $command
+    }
+} catch {
+    Write-Log ("ERROR: " + $_.Exception.Message)
+    Write-Log "stopping, the work files are in $WorkDir"
+    exit 1
+}
+Write-Log "duplicate clean up finished"
+Remove-Item -Recurse -Force $WorkDir
+`
+
+// powerShellString returns the value as a single quoted PowerShell string
+func powerShellString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// powerShellFields returns the fields of the change as an array of PowerShell hashtables
+func (c scriptChange) powerShellFields() string {
+	fields := []string{}
+	for _, f := range c.fields {
+		path := []string{}
+		for _, p := range f.path {
+			path = append(path, powerShellString(p))
+		}
+		fields = append(fields, fmt.Sprintf("@{ Path = @(%s); Value = %s }", strings.Join(path, ", "), powerShellString(f.value)))
+	}
+	return "@(" + strings.Join(fields, ", ") + ")"
+}
+
+// powerShellScript renders the actions as a PowerShell script with a function per action, using ConvertFrom-Json rather than jq
+func powerShellScript(actions []*Action, centralURL, tenantID string) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, powerShellPreamble, scriptFiles[scriptPowerShell], powerShellString(centralURL), powerShellString(tenantID))
+
+	ids := []string{}
+	for _, a := range actions {
+		if len(a.Steps) == 0 {
+			fmt.Fprintf(b, "\n# ACTION %s has no steps, review it in the actions log\n", a.ID)
+			continue
+		}
+		ids = append(ids, powerShellString(a.ID))
+		fmt.Fprintf(b, "\nfunction Invoke-Action%s {\n", a.ID)
+		fmt.Fprintf(b, "    Write-Log %s\n", powerShellString(actionDescription(a)))
+		for _, s := range a.Steps {
+			c := s.change()
+			switch {
+			case c.republish:
+				fmt.Fprintf(b, "    Publish-Asset %s %s\n", powerShellString(c.name), powerShellString(c.selfLink))
+			case c.delete:
+				fmt.Fprintf(b, "    Remove-Resource %s %s %s\n", c.kind, powerShellString(c.scope), powerShellString(c.name))
+			case c.subResource != "":
+				fmt.Fprintf(b, "    Update-SubResource %s %s %s %s %s %s\n", c.kind, powerShellString(c.scope), powerShellString(c.name),
+					powerShellString(c.selfLink), powerShellString(c.subResource), c.powerShellFields())
+			default:
+				fmt.Fprintf(b, "    Update-Resource %s %s %s %s\n", c.kind, powerShellString(c.scope), powerShellString(c.name), c.powerShellFields())
+			}
+		}
+		fmt.Fprintf(b, "    Write-Log %s\n", powerShellString(fmt.Sprintf("ACTION %s finished", a.ID)))
+		b.WriteString("}\n")
+	}
+
+	fmt.Fprintf(b, powerShellMain, strings.Join(ids, ", "))
+	return b.String()
+}
@@ -5,6 +5,17 @@ import "github.com/vivekschauhan/amplify-tool/pkg/tools"
 // Config the configuration for the Watch client
 type Config struct {
 	tools.Config
-	Environment string `mapstructure:"environment"`
-	OutFile     string `mapstructure:"out_file"`
+	Environment    string `mapstructure:"environment"`
+	Environments   string `mapstructure:"environments"`
+	PerEnvironment bool   `mapstructure:"per_environment"`
+	OutFile        string `mapstructure:"out_file"`
+	Format         string `mapstructure:"format"`
+	ExtractSpecs   bool   `mapstructure:"extract_specs"`
+	SpecsDir       string `mapstructure:"specs_dir"`
+	SinceFile      string `mapstructure:"since_file"`
+	KeepOwners     bool   `mapstructure:"keep_owners"`
+	TeamsFile      string `mapstructure:"teams_file"`
+	Query          string `mapstructure:"query"`
+	Tags           string `mapstructure:"tags"`
+	Attributes     string `mapstructure:"attributes"`
 }
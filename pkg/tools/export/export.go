@@ -1,8 +1,14 @@
 package export
 
 import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
 	"github.com/Axway/agent-sdk/pkg/apic"
 	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	sdkcmd "github.com/Axway/agent-sdk/pkg/cmd"
 	utillog "github.com/Axway/agent-sdk/pkg/util/log"
 	"github.com/sirupsen/logrus"
 	"github.com/vivekschauhan/amplify-tool/pkg/log"
@@ -15,11 +21,10 @@ type Tool interface {
 }
 
 type tool struct {
-	apicClient      apic.Client
-	cfg             *Config
-	logger          *logrus.Logger
-	assetCatalog    service.AssetCatalog
-	serviceRegistry service.ServiceRegistry
+	apicClient apic.Client
+	cfg        *Config
+	logger     *logrus.Logger
+	filter     *service.ResourceFilter
 }
 
 func NewTool(cfg *Config) Tool {
@@ -28,32 +33,234 @@ func NewTool(cfg *Config) Tool {
 	utillog.GlobalLoggerConfig.Level(cfg.Level).
 		Format(cfg.Format).
 		Apply()
-	serviceRegistry := service.NewServiceRegistry(logger, apicClient, cfg.DryRun,
-		service.WithGetInstances(),
-		service.WithEnvironments([]string{cfg.Environment}),
-		service.WithOutputFile(cfg.OutFile),
-		service.WithIncludeData(false),
-	)
-	assetCatalog := service.NewAssetCatalog(logger, apicClient, cfg.DryRun, serviceRegistry, service.WithFilterUsingRegistry(), service.ForExport(), service.StripData())
 	return &tool{
-		logger:          logger,
-		cfg:             cfg,
-		apicClient:      apicClient,
-		serviceRegistry: serviceRegistry,
-		assetCatalog:    assetCatalog,
+		logger:     logger,
+		cfg:        cfg,
+		apicClient: apicClient,
 	}
 }
 
 func (t *tool) Run() error {
 	t.logger.Info("Amplify Export Tool")
+	filter, err := service.NewResourceFilter(t.cfg.Query, t.cfg.Tags, t.cfg.Attributes)
+	if err != nil {
+		t.logger.WithError(err).Error("could not parse filter: stopping the tool")
+		return err
+	}
+	if !filter.IsEmpty() {
+		t.logger.WithField("query", filter.String()).Info("filtering APIServices and Assets")
+	}
+	t.filter = filter
+
+	envs, err := service.ResolveEnvironments(t.apicClient, t.environmentPatterns())
+	if err != nil {
+		t.logger.WithError(err).Error("could not read environments: stopping the tool")
+		return err
+	}
+	if len(envs) == 0 {
+		err = fmt.Errorf("no environments matched %s", strings.Join(t.environmentPatterns(), ","))
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	sort.Strings(envs)
+	t.logger.WithField("environments", envs).Info("exporting environments")
+
+	if t.cfg.KeepOwners {
+		if err := t.writeTeams(); err != nil {
+			return err
+		}
+	}
+
+	outFile := t.outputFileName()
+	if !t.cfg.PerEnvironment {
+		resources, err := t.export(outFile, envs)
+		if err != nil {
+			t.logger.WithError(err).Error("could not read resources: stopping the tool")
+			return err
+		}
+		return t.write(outFile, t.cfg.SinceFile, envs, resources)
+	}
+
+	for _, env := range envs {
+		envOutFile := environmentFileName(outFile, env)
+		sinceFile := ""
+		if t.cfg.SinceFile != "" {
+			sinceFile = environmentFileName(t.cfg.SinceFile, env)
+		}
+		resources, err := t.export(envOutFile, []string{env})
+		if err != nil {
+			t.logger.WithError(err).WithField("env", env).Error("could not read resources: stopping the tool")
+			return err
+		}
+		if err := t.write(envOutFile, sinceFile, []string{env}, resources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputFileName returns the out file with the extension matching the format, or without one for a directory
+func (t *tool) outputFileName() string {
+	ext := filepath.Ext(t.cfg.OutFile)
+	switch t.cfg.Format {
+	case service.FormatYAML:
+		if ext == ".json" {
+			return strings.TrimSuffix(t.cfg.OutFile, ext) + ".yaml"
+		}
+	case service.FormatDir:
+		return strings.TrimSuffix(t.cfg.OutFile, ext)
+	}
+	return t.cfg.OutFile
+}
+
+// writeTeams saves the teams of the org so that owners can be matched to teams in another org by name on import
+func (t *tool) writeTeams() error {
+	teams, err := t.apicClient.GetTeam(map[string]string{})
+	if err != nil {
+		t.logger.WithError(err).Error("unable to read teams")
+		return err
+	}
+	t.logger.WithField("file", t.cfg.TeamsFile).WithField("teams", len(teams)).Info("writing teams")
+	service.SaveToFile(t.logger, "teams", t.cfg.TeamsFile, teams)
+	return nil
+}
+
+// environmentPatterns returns the environment names and glob patterns set in the config
+func (t *tool) environmentPatterns() []string {
+	patterns := []string{}
+	if t.cfg.Environment != "" {
+		patterns = append(patterns, t.cfg.Environment)
+	}
+	for _, env := range strings.Split(t.cfg.Environments, ",") {
+		env = strings.Trim(env, " ")
+		if env != "" {
+			patterns = append(patterns, env)
+		}
+	}
+	return patterns
+}
+
+// export reads and returns the resources for the environments, spec files are written next to the out file. An error
+// is returned when the products could not all be read, so that an incomplete export is not written.
+func (t *tool) export(outFile string, envs []string) ([]v1.Interface, error) {
+	specsDir := ""
+	if t.cfg.ExtractSpecs {
+		specsDir = t.cfg.SpecsDir
+	}
+	serviceRegistry := service.NewServiceRegistry(t.logger, t.apicClient, t.cfg.DryRun,
+		service.WithGetInstances(),
+		service.WithEnvironments(envs),
+		service.WithIncludeData(false),
+		service.WithSpecFiles(filepath.Dir(outFile), specsDir),
+		service.WithKeepOwners(t.cfg.KeepOwners),
+		service.WithServiceFilter(t.filter),
+	)
+	assetCatalog := service.NewAssetCatalog(t.logger, t.apicClient, t.cfg.DryRun, serviceRegistry, service.WithFilterUsingRegistry(), service.ForExport(), service.StripData(), service.WithAssetOwners(t.cfg.KeepOwners), service.WithAssetFilter(t.filter))
+	productCatalog := service.NewProductCatalog(t.logger, assetCatalog, t.apicClient, "", t.cfg.DryRun, service.WithProductDocuments(), service.StripProductData(), service.WithProductOwners(t.cfg.KeepOwners))
+
 	resources := []v1.Interface{}
-	t.serviceRegistry.ReadServices()
-	svcRes := t.serviceRegistry.GetServicesOutput()
+	serviceRegistry.ReadServices()
+	svcRes := serviceRegistry.GetServicesOutput()
 	resources = append(resources, svcRes...)
-	t.assetCatalog.ReadAssets(false)
-	catRes := t.assetCatalog.GetAssetOutput()
+	assetCatalog.ReadAssets(false)
+	catRes := assetCatalog.GetAssetOutput()
 	resources = append(resources, catRes...)
+	if err := productCatalog.ReadProducts(); err != nil {
+		return nil, err
+	}
+	prodRes := productCatalog.GetProductOutput()
+	resources = append(resources, prodRes...)
+
+	return dedupeResources(resources), nil
+}
 
-	service.SaveToFile(t.logger, "export", "export.json", resources)
+// write saves the resources, or the changes since the previous export when sinceFile is set, and the manifest
+func (t *tool) write(fileName, sinceFile string, envs []string, resources []v1.Interface) error {
+	logger := t.logger.WithField("file", fileName).WithField("format", t.cfg.Format)
+	if sinceFile != "" {
+		return t.writeChangeset(logger, fileName, sinceFile, envs, resources)
+	}
+
+	logger.WithField("resources", len(resources)).Info("writing export")
+	err := service.SaveResources(t.logger, fileName, t.cfg.Format, resources)
+	if err != nil {
+		logger.WithError(err).Error("unable to write export")
+		return err
+	}
+
+	manifest, err := service.NewManifest(fileName, t.cfg.Format, resources)
+	if err != nil {
+		logger.WithError(err).Error("unable to create the export manifest")
+		return err
+	}
+	t.writeManifest(fileName, envs, manifest)
 	return nil
 }
+
+func (t *tool) writeManifest(fileName string, envs []string, manifest *service.Manifest) {
+	manifest.ToolVersion = sdkcmd.BuildVersion
+	manifest.OrgID = t.cfg.OrgID
+	manifest.Region = t.cfg.Region
+	manifest.Environments = envs
+	manifestFile := service.ManifestFileName(fileName)
+	t.logger.WithField("manifest", manifestFile).Info("writing export manifest")
+	service.SaveToFile(t.logger, "manifest", manifestFile, manifest)
+}
+
+func (t *tool) writeChangeset(logger *logrus.Entry, fileName, sinceFile string, envs []string, resources []v1.Interface) error {
+	logger = logger.WithField("sinceFile", sinceFile)
+	previous, err := service.ReadResources(sinceFile)
+	if err != nil {
+		logger.WithError(err).Error("unable to read the previous export")
+		return err
+	}
+	cs, err := service.NewChangeset(previous, resources)
+	if err != nil {
+		logger.WithError(err).Error("unable to compare to the previous export")
+		return err
+	}
+
+	logger.
+		WithField("create", len(cs.Create)).
+		WithField("update", len(cs.Update)).
+		WithField("delete", len(cs.Delete)).
+		Info("writing export changeset")
+	err = service.SaveChangeset(t.logger, fileName, t.cfg.Format, cs)
+	if err != nil {
+		logger.WithError(err).Error("unable to write export changeset")
+		return err
+	}
+
+	manifest, err := service.NewChangesetManifest(fileName, t.cfg.Format, cs)
+	if err != nil {
+		logger.WithError(err).Error("unable to create the export manifest")
+		return err
+	}
+	t.writeManifest(fileName, envs, manifest)
+	return nil
+}
+
+// dedupeResources removes resources with the same kind, scope and name, keeping the first found
+func dedupeResources(resources []v1.Interface) []v1.Interface {
+	seen := map[string]struct{}{}
+	deduped := []v1.Interface{}
+	for _, res := range resources {
+		ri, err := res.AsInstance()
+		if err != nil {
+			continue
+		}
+		key := service.ResourceKey(ri)
+		if _, found := seen[key]; found {
+			continue
+		}
+		seen[key] = struct{}{}
+		deduped = append(deduped, res)
+	}
+	return deduped
+}
+
+// environmentFileName adds the environment name to the file name, before the extension
+func environmentFileName(fileName, env string) string {
+	ext := filepath.Ext(fileName)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(fileName, ext), env, ext)
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"github.com/Axway/agent-sdk/pkg/apic/auth"
+	"github.com/vivekschauhan/amplify-tool/pkg/tools"
+)
+
+// Config the configuration for the Watch client
+type Config struct {
+	tools.Config
+	InFile       string      `mapstructure:"in_file"`
+	ResultsFile  string      `mapstructure:"results_file"`
+	ManifestFile string      `mapstructure:"manifest_file"`
+	Force        bool        `mapstructure:"force"`
+	EnvMap       string      `mapstructure:"env_map"`
+	OnConflict   string      `mapstructure:"on_conflict"`
+	RenameSuffix string      `mapstructure:"rename_suffix"`
+	KeepOwners   bool        `mapstructure:"keep_owners"`
+	TeamMapFile  string      `mapstructure:"team_map_file"`
+	TeamsFile    string      `mapstructure:"teams_file"`
+	TargetOrgID  string      `mapstructure:"target_org_id"`
+	TargetRegion string      `mapstructure:"target_region"`
+	TargetURL    string      `mapstructure:"target_url"`
+	TargetAuth   auth.Config `mapstructure:"target_auth"`
+}
+
+// hasTarget returns true when any of the target options are set, the import is then into another org
+func (c *Config) hasTarget() bool {
+	return c.TargetOrgID != "" || c.TargetAuth.ClientID != "" || c.TargetRegion != "" || c.TargetURL != ""
+}
+
+// targetConfig returns the config for the org to import into, the base config with each of the target options that
+// are set
+func (c *Config) targetConfig() *tools.Config {
+	target := c.Config
+	if c.TargetOrgID != "" {
+		target.OrgID = c.TargetOrgID
+	}
+	if c.TargetAuth.ClientID != "" {
+		target.Auth = c.TargetAuth
+	}
+	if c.TargetRegion != "" {
+		target.Region = c.TargetRegion
+		target.URL = ""
+		target.PlatformURL = ""
+		target.SingleURL = ""
+		target.TraceabilityHost = ""
+	}
+	if c.TargetURL != "" {
+		target.URL = c.TargetURL
+	}
+	return &target
+}
@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+)
+
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictRename    = "rename"
+	conflictFail      = "fail"
+
+	maxRenameAttempts = 10
+)
+
+// plannedResource is a resource to import along with the outcome of its conflict check
+type plannedResource struct {
+	ri          *v1.ResourceInstance
+	exists      bool
+	renamedFrom string
+}
+
+func validateConflictPolicy(policy string) error {
+	switch policy {
+	case conflictSkip, conflictOverwrite, conflictRename, conflictFail:
+		return nil
+	}
+	return fmt.Errorf("unknown conflict policy %s, expected one of %s, %s, %s or %s", policy, conflictSkip, conflictOverwrite, conflictRename, conflictFail)
+}
+
+func renameKey(kind, scope, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, scope, name)
+}
+
+func (t *tool) exists(ri *v1.ResourceInstance) (bool, error) {
+	existing, err := service.ExistingResource(t.apicClient, ri)
+	if err != nil {
+		return false, fmt.Errorf("unable to check %s %s in the target: %w", ri.Kind, ri.Name, err)
+	}
+	return existing != nil, nil
+}
+
+// plan checks each resource, in apply order, against the target and applies the conflict policy. Renamed resources
+// are recorded so that the references to them from resources later in the order are updated as well.
+func (t *tool) plan(resources []*v1.ResourceInstance) ([]plannedResource, error) {
+	renames := map[string]string{}
+	planned := []plannedResource{}
+	conflicts := []plannedResource{}
+	for _, ri := range resources {
+		ri = applyRenames(renames, ri)
+		p := plannedResource{ri: ri}
+		if !service.IsApplyKind(ri.Kind) {
+			planned = append(planned, p)
+			continue
+		}
+
+		exists, err := t.exists(ri)
+		if err != nil {
+			return nil, err
+		}
+		p.exists = exists
+		if p.exists {
+			switch t.cfg.OnConflict {
+			case conflictFail:
+				conflicts = append(conflicts, p)
+				continue
+			case conflictRename:
+				newName, err := t.uniqueName(ri)
+				if err != nil {
+					return nil, err
+				}
+				renames[renameKey(ri.Kind, ri.Metadata.Scope.Name, ri.Name)] = newName
+				p.renamedFrom = ri.Name
+				p.exists = false
+				ri.Name = newName
+				t.logger.
+					WithField("kind", ri.Kind).
+					WithField("scope", ri.Metadata.Scope.Name).
+					WithField("name", p.renamedFrom).
+					WithField("newName", newName).
+					Info("resource exists, renaming")
+			}
+		}
+		planned = append(planned, p)
+	}
+
+	if len(conflicts) == 0 {
+		return planned, nil
+	}
+
+	// nothing is applied when a conflict is found with the fail policy
+	for _, p := range conflicts {
+		t.results = append(t.results, t.newResult(p, actionFailed, "resource already exists"))
+	}
+	for _, p := range planned {
+		t.results = append(t.results, t.newResult(p, actionSkipped, "import stopped on conflict"))
+	}
+	return nil, fmt.Errorf("%d resources already exist in the target", len(conflicts))
+}
+
+// uniqueName finds a name, using the rename suffix, that is not in use in the target
+func (t *tool) uniqueName(ri *v1.ResourceInstance) (string, error) {
+	probe := *ri
+	for i := 1; i <= maxRenameAttempts; i++ {
+		probe.Name = ri.Name + t.cfg.RenameSuffix
+		if i > 1 {
+			probe.Name = fmt.Sprintf("%s%s-%d", ri.Name, t.cfg.RenameSuffix, i)
+		}
+		exists, err := t.exists(&probe)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return probe.Name, nil
+		}
+	}
+	return "", fmt.Errorf("unable to find an unused name for %s %s", ri.Kind, ri.Name)
+}
+
+// applyRenames updates the scope and references of the resource to the new names of renamed resources
+func applyRenames(renames map[string]string, ri *v1.ResourceInstance) *v1.ResourceInstance {
+	if len(renames) == 0 {
+		return ri
+	}
+
+	scope := ri.Metadata.Scope
+	if newName, found := renames[renameKey(scope.Kind, "", scope.Name)]; found {
+		ri.Metadata.Scope.Name = newName
+	}
+
+	env := ri.Metadata.Scope.Name
+	switch ri.Kind {
+	case management.APIServiceRevisionGVK().Kind:
+		rev := management.NewAPIServiceRevision("", env)
+		rev.FromInstance(ri)
+		rev.Spec.ApiService = renamedReference(renames, management.APIServiceGVK().Kind, env, rev.Spec.ApiService)
+		ri, _ = rev.AsInstance()
+	case management.APIServiceInstanceGVK().Kind:
+		inst := management.NewAPIServiceInstance("", env)
+		inst.FromInstance(ri)
+		inst.Spec.ApiServiceRevision = renamedReference(renames, management.APIServiceRevisionGVK().Kind, env, inst.Spec.ApiServiceRevision)
+		ri, _ = inst.AsInstance()
+	case catalog.AssetMappingGVK().Kind:
+		am := catalog.NewAssetMapping("", ri.Metadata.Scope.Name)
+		am.FromInstance(ri)
+		am.Spec.Inputs.ApiService = renamedReference(renames, management.APIServiceGVK().Kind, "", am.Spec.Inputs.ApiService)
+		am.Spec.Inputs.ApiServiceRevision = renamedReference(renames, management.APIServiceRevisionGVK().Kind, "", am.Spec.Inputs.ApiServiceRevision)
+		ri, _ = am.AsInstance()
+	case catalog.ProductGVK().Kind:
+		p := catalog.NewProduct("")
+		p.FromInstance(ri)
+		for i := range p.Spec.Assets {
+			p.Spec.Assets[i].Name = renamedReference(renames, catalog.AssetGVK().Kind, "", p.Spec.Assets[i].Name)
+		}
+		ri, _ = p.AsInstance()
+	case catalog.ProductPlanGVK().Kind:
+		plan := catalog.NewProductPlan("")
+		plan.FromInstance(ri)
+		plan.Spec.Product = renamedReference(renames, catalog.ProductGVK().Kind, "", plan.Spec.Product)
+		ri, _ = plan.AsInstance()
+	}
+	return ri
+}
+
+// renamedReference returns the reference, in the name, env/name or group/env/name form, with the new name of the
+// referenced resource when it was renamed
+func renamedReference(renames map[string]string, kind, scope, ref string) string {
+	elements := strings.Split(ref, "/")
+	if len(elements) >= 2 {
+		scope = elements[len(elements)-2]
+	}
+	if newName, found := renames[renameKey(kind, scope, elements[len(elements)-1])]; found {
+		elements[len(elements)-1] = newName
+	}
+	return strings.Join(elements, "/")
+}
@@ -0,0 +1,36 @@
+package importer
+
+import "testing"
+
+func TestRenamedReference(t *testing.T) {
+	renames := map[string]string{
+		renameKey("APIService", "env", "petstore"):          "petstore-2",
+		renameKey("APIServiceRevision", "env", "petstore"):  "petstore-rev-2",
+		renameKey("Product", "", "payments"):                "payments-2",
+		renameKey("APIService", "other-env", "petstore-v2"): "petstore-v2-2",
+	}
+	tests := []struct {
+		name  string
+		kind  string
+		scope string
+		ref   string
+		want  string
+	}{
+		{name: "name in the scope", kind: "APIService", scope: "env", ref: "petstore", want: "petstore-2"},
+		{name: "name not renamed", kind: "APIService", scope: "env", ref: "orders", want: "orders"},
+		{name: "name in another scope", kind: "APIService", scope: "other-env", ref: "petstore", want: "petstore"},
+		{name: "env/name uses the env of the reference", kind: "APIService", scope: "", ref: "env/petstore", want: "env/petstore-2"},
+		{name: "group/env/name", kind: "APIService", scope: "", ref: "management/other-env/petstore-v2", want: "management/other-env/petstore-v2-2"},
+		{name: "env/name in another env", kind: "APIService", scope: "env", ref: "other-env/petstore", want: "other-env/petstore"},
+		{name: "kind of the reference", kind: "APIServiceRevision", scope: "env", ref: "petstore", want: "petstore-rev-2"},
+		{name: "unscoped resource", kind: "Product", scope: "", ref: "payments", want: "payments-2"},
+		{name: "empty reference", kind: "APIService", scope: "env", ref: "", want: ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := renamedReference(renames, tc.kind, tc.scope, tc.ref); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Axway/agent-sdk/pkg/apic"
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	utillog "github.com/Axway/agent-sdk/pkg/util/log"
+	"github.com/sirupsen/logrus"
+	"github.com/vivekschauhan/amplify-tool/pkg/log"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+	"github.com/vivekschauhan/amplify-tool/pkg/tools"
+)
+
+const (
+	actionCreated = "created"
+	actionRenamed = "renamed"
+	actionUpdated = "updated"
+	actionSkipped = "skipped"
+	actionFailed  = "failed"
+)
+
+type Tool interface {
+	Run() error
+}
+
+type result struct {
+	Kind        string `json:"kind"`
+	Scope       string `json:"scope,omitempty"`
+	Name        string `json:"name"`
+	RenamedFrom string `json:"renamedFrom,omitempty"`
+	Action      string `json:"action"`
+	DryRun      bool   `json:"dryRun,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type tool struct {
+	apicClient   apic.Client
+	sourceClient apic.Client
+	cfg          *Config
+	logger       *logrus.Logger
+	envMap       map[string]string
+	results      []result
+}
+
+func NewTool(cfg *Config) Tool {
+	logger := log.GetLogger(cfg.Level, cfg.Format)
+	apicClient, _ := tools.CreateAPICClient(cfg.targetConfig())
+	var sourceClient apic.Client
+	if cfg.hasTarget() {
+		sourceClient, _ = tools.CreateAPICClient(&cfg.Config)
+	}
+	utillog.GlobalLoggerConfig.Level(cfg.Level).
+		Format(cfg.Format).
+		Apply()
+	return &tool{
+		logger:       logger,
+		cfg:          cfg,
+		apicClient:   apicClient,
+		sourceClient: sourceClient,
+		results:      []result{},
+	}
+}
+
+func (t *tool) Run() error {
+	t.logger.Info("Amplify Import Tool")
+	envMap, err := parseEnvMap(t.cfg.EnvMap)
+	if err != nil {
+		t.logger.WithError(err).Error("could not parse environment mapping: stopping the tool")
+		return err
+	}
+	t.envMap = envMap
+
+	if err := validateConflictPolicy(t.cfg.OnConflict); err != nil {
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+
+	if err := t.verifyExport(); err != nil {
+		t.logger.WithError(err).Error("could not verify export file: stopping the tool")
+		return err
+	}
+
+	resources, err := t.readExport()
+	if err != nil {
+		t.logger.WithError(err).Error("could not read export file: stopping the tool")
+		return err
+	}
+
+	resources, err = t.inlineSpecs(resources)
+	if err != nil {
+		t.logger.WithError(err).Error("could not read spec files: stopping the tool")
+		return err
+	}
+	resources = t.remapEnvironments(resources)
+	if err := t.remapOwners(resources); err != nil {
+		t.logger.WithError(err).Error("could not remap owners: stopping the tool")
+		return err
+	}
+	planned, err := t.plan(service.SortByKind(resources))
+	if err == nil {
+		for _, p := range planned {
+			t.results = append(t.results, t.apply(p))
+		}
+	} else {
+		t.logger.WithError(err).Error("could not plan the import: nothing was imported")
+	}
+
+	t.summarize()
+	if t.cfg.ResultsFile != "" {
+		service.SaveToFile(t.logger, "import-results", t.cfg.ResultsFile, t.results)
+	}
+	return err
+}
+
+func (t *tool) readExport() ([]*v1.ResourceInstance, error) {
+	// the delete section of a changeset would be created, and the other formats are not read as resources at all
+	changeset, err := service.IsChangeset(t.cfg.InFile)
+	if err != nil {
+		return nil, err
+	}
+	if changeset {
+		return nil, fmt.Errorf("%s is a changeset written with since_file, import a full export instead", t.cfg.InFile)
+	}
+	resources, err := service.ReadResources(t.cfg.InFile)
+	if err != nil {
+		return nil, err
+	}
+	t.logger.WithField("file", t.cfg.InFile).WithField("resources", len(resources)).Info("read export file")
+	return resources, nil
+}
+
+// verifyExport checks the export file against the manifest written by export, a mismatch stops the import unless forced
+func (t *tool) verifyExport() error {
+	manifestFile := t.cfg.ManifestFile
+	if manifestFile == "" {
+		manifestFile = service.ManifestFileName(t.cfg.InFile)
+	}
+	logger := t.logger.WithField("file", t.cfg.InFile).WithField("manifest", manifestFile)
+
+	if _, err := os.Stat(manifestFile); os.IsNotExist(err) && t.cfg.ManifestFile == "" {
+		if t.cfg.Force {
+			logger.Warn("no manifest found, importing the unverified export file as force is set")
+			return nil
+		}
+		return fmt.Errorf("no manifest found for export %s, set force to import it anyway", t.cfg.InFile)
+	}
+	manifest, err := service.ReadManifest(manifestFile)
+	if err != nil {
+		return err
+	}
+	if manifest.Changeset {
+		return fmt.Errorf("%s is a changeset written with since_file, import a full export instead", t.cfg.InFile)
+	}
+	problems, err := service.VerifyManifest(manifest, t.cfg.InFile)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		logger.Warn(problem)
+	}
+	if len(problems) == 0 {
+		logger.Info("export file matches its manifest")
+		return nil
+	}
+	if t.cfg.Force {
+		logger.Warn("export file does not match its manifest, importing anyway as force is set")
+		return nil
+	}
+	return fmt.Errorf("export %s does not match its manifest, set force to import it anyway", t.cfg.InFile)
+}
+
+// inlineSpecs replaces the spec file references in revisions, written by the export extract_specs option, with the specs
+func (t *tool) inlineSpecs(resources []*v1.ResourceInstance) ([]*v1.ResourceInstance, error) {
+	baseDir := filepath.Dir(t.cfg.InFile)
+	for i, ri := range resources {
+		if ri.Kind != management.APIServiceRevisionGVK().Kind {
+			continue
+		}
+		rev := management.NewAPIServiceRevision("", ri.Metadata.Scope.Name)
+		rev.FromInstance(ri)
+		if !strings.HasPrefix(rev.Spec.Definition.Value, service.SpecFilePrefix) {
+			continue
+		}
+		if err := service.InlineSpecFile(baseDir, rev); err != nil {
+			return nil, err
+		}
+		resources[i], _ = rev.AsInstance()
+	}
+	return resources, nil
+}
+
+func (t *tool) newResult(p plannedResource, action, errMsg string) result {
+	return result{
+		Kind:        p.ri.Kind,
+		Scope:       p.ri.Metadata.Scope.Name,
+		Name:        p.ri.Name,
+		RenamedFrom: p.renamedFrom,
+		Action:      action,
+		DryRun:      t.cfg.DryRun,
+		Error:       errMsg,
+	}
+}
+
+func (t *tool) apply(p plannedResource) result {
+	ri := p.ri
+	logger := t.logger.
+		WithField("kind", ri.Kind).
+		WithField("scope", ri.Metadata.Scope.Name).
+		WithField("name", ri.Name)
+
+	if !service.IsApplyKind(ri.Kind) {
+		logger.Warn("skipping resource of unsupported kind")
+		return t.newResult(p, actionSkipped, "")
+	}
+
+	res := t.newResult(p, actionCreated, "")
+	switch {
+	case p.renamedFrom != "":
+		res.Action = actionRenamed
+	case p.exists && t.cfg.OnConflict == conflictSkip:
+		logger.Info("resource exists, skipping")
+		res.Action = actionSkipped
+		return res
+	case p.exists:
+		res.Action = actionUpdated
+	}
+
+	logger = logger.WithField("action", res.Action)
+	if t.cfg.DryRun {
+		logger.Info("dry run, resource not applied")
+		return res
+	}
+
+	if err := service.ApplyResource(t.apicClient, ri, res.Action == actionUpdated); err != nil {
+		logger.WithError(err).Error("unable to apply resource")
+		res.Action = actionFailed
+		res.Error = err.Error()
+		return res
+	}
+
+	logger.Info("applied resource")
+	return res
+}
+
+func (t *tool) summarize() {
+	counts := map[string]map[string]int{}
+	for _, res := range t.results {
+		if _, found := counts[res.Kind]; !found {
+			counts[res.Kind] = map[string]int{}
+		}
+		counts[res.Kind][res.Action]++
+	}
+	for kind, actions := range counts {
+		t.logger.WithField("kind", kind).WithFields(toFields(actions)).Info("import summary")
+	}
+}
+
+func toFields(actions map[string]int) logrus.Fields {
+	fields := logrus.Fields{}
+	for action, count := range actions {
+		fields[action] = count
+	}
+	return fields
+}
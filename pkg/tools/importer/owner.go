@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	"github.com/Axway/agent-sdk/pkg/apic/definitions"
+)
+
+// teamResolver maps the owning teams of the source org to the teams of the target org
+type teamResolver struct {
+	teamMap     map[string]string
+	sourceNames map[string]string
+	targetIDs   map[string]string
+}
+
+func (t *tool) newTeamResolver() (*teamResolver, error) {
+	r := &teamResolver{
+		teamMap:     map[string]string{},
+		sourceNames: map[string]string{},
+		targetIDs:   map[string]string{},
+	}
+
+	if t.cfg.TeamMapFile != "" {
+		buf, err := os.ReadFile(t.cfg.TeamMapFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(buf, &r.teamMap); err != nil {
+			return nil, err
+		}
+	}
+
+	sourceTeams, err := t.sourceTeams()
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range sourceTeams {
+		r.sourceNames[team.ID] = team.Name
+	}
+
+	targetTeams, err := t.apicClient.GetTeam(map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range targetTeams {
+		r.targetIDs[team.ID] = team.ID
+		r.targetIDs[team.Name] = team.ID
+	}
+	return r, nil
+}
+
+// sourceTeams reads the source org teams from the teams file written by export, or from the source org
+func (t *tool) sourceTeams() ([]definitions.PlatformTeam, error) {
+	teams := []definitions.PlatformTeam{}
+	if t.cfg.TeamsFile != "" {
+		buf, err := os.ReadFile(t.cfg.TeamsFile)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(buf, &teams)
+		return teams, err
+	}
+	if t.sourceClient != nil {
+		return t.sourceClient.GetTeam(map[string]string{})
+	}
+	return teams, nil
+}
+
+// resolve returns the target team ID for a source team ID. The team map is checked using the source team ID and then
+// its name, before falling back to a target team with the same name, or the same ID when importing into the same org.
+func (r *teamResolver) resolve(sourceID string) (string, bool) {
+	name := r.sourceNames[sourceID]
+	for _, key := range []string{sourceID, name} {
+		if key == "" {
+			continue
+		}
+		if mapped, found := r.teamMap[key]; found {
+			id, found := r.targetIDs[mapped]
+			return id, found
+		}
+	}
+	if name != "" {
+		if id, found := r.targetIDs[name]; found {
+			return id, true
+		}
+	}
+	id, found := r.targetIDs[sourceID]
+	return id, found
+}
+
+// remapOwners sets the owner of each resource to the matching team in the target org, owners that can not be
+// matched are removed so the resources are created without an owner
+func (t *tool) remapOwners(resources []*v1.ResourceInstance) error {
+	if !t.cfg.KeepOwners {
+		for _, ri := range resources {
+			ri.Owner = nil
+		}
+		return nil
+	}
+
+	resolver, err := t.newTeamResolver()
+	if err != nil {
+		return err
+	}
+	for _, ri := range resources {
+		if ri.Owner == nil {
+			continue
+		}
+		logger := t.logger.
+			WithField("kind", ri.Kind).
+			WithField("scope", ri.Metadata.Scope.Name).
+			WithField("name", ri.Name).
+			WithField("sourceOwnerID", ri.Owner.ID)
+		if ri.Owner.Type != v1.TeamOwner {
+			logger.Warn("only team owners can be remapped, removing owner")
+			ri.Owner = nil
+			continue
+		}
+		targetID, found := resolver.resolve(ri.Owner.ID)
+		if !found {
+			logger.Warn("no matching team in the target org, removing owner")
+			ri.Owner = nil
+			continue
+		}
+		logger.WithField("targetOwnerID", targetID).Debug("remapped owner")
+		ri.Owner = &v1.Owner{
+			Type: v1.TeamOwner,
+			ID:   targetID,
+		}
+	}
+	return nil
+}
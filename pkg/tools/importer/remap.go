@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+)
+
+// parseEnvMap parses comma separated source=target pairs
+func parseEnvMap(envMap string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if envMap == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(envMap, ",") {
+		elements := strings.Split(strings.Trim(pair, " "), "=")
+		if len(elements) != 2 || elements[0] == "" || elements[1] == "" {
+			return nil, fmt.Errorf("invalid environment mapping %q, expected source=target", pair)
+		}
+		mapping[elements[0]] = elements[1]
+	}
+	return mapping, nil
+}
+
+// remapEnvironments moves every resource scoped to a source environment to its target environment
+// and rewrites the references between the resources to match
+func (t *tool) remapEnvironments(resources []*v1.ResourceInstance) []*v1.ResourceInstance {
+	if len(t.envMap) == 0 {
+		return resources
+	}
+
+	remapped := make([]*v1.ResourceInstance, 0, len(resources))
+	for _, ri := range resources {
+		if ri.Metadata.Scope.Kind == management.EnvironmentGVK().Kind {
+			if target, found := t.envMap[ri.Metadata.Scope.Name]; found {
+				t.logger.
+					WithField("kind", ri.Kind).
+					WithField("name", ri.Name).
+					WithField("sourceEnv", ri.Metadata.Scope.Name).
+					WithField("targetEnv", target).
+					Debug("remapping resource environment")
+				ri.Metadata.Scope.Name = target
+			}
+		}
+
+		switch ri.Kind {
+		case management.APIServiceInstanceGVK().Kind:
+			inst := management.NewAPIServiceInstance("", ri.Metadata.Scope.Name)
+			inst.FromInstance(ri)
+			inst.Spec.ApiServiceRevision = t.remapReference(inst.Spec.ApiServiceRevision)
+			ri, _ = inst.AsInstance()
+		case catalog.AssetMappingGVK().Kind:
+			am := catalog.NewAssetMapping("", ri.Metadata.Scope.Name)
+			am.FromInstance(ri)
+			am.Spec.Inputs.ApiService = t.remapReference(am.Spec.Inputs.ApiService)
+			am.Spec.Inputs.ApiServiceRevision = t.remapReference(am.Spec.Inputs.ApiServiceRevision)
+			ri, _ = am.AsInstance()
+		}
+		remapped = append(remapped, ri)
+	}
+	return remapped
+}
+
+// remapReference rewrites the environment of a reference in either the group/env/name or env/name form
+func (t *tool) remapReference(ref string) string {
+	elements := strings.Split(ref, "/")
+	envIndex := len(elements) - 2
+	if envIndex < 0 {
+		return ref
+	}
+	if target, found := t.envMap[elements[envIndex]]; found {
+		elements[envIndex] = target
+	}
+	return strings.Join(elements, "/")
+}
@@ -42,7 +42,10 @@ func NewTool(cfg *Config) Tool {
 func (t *tool) Run() error {
 	t.logger.Info("Amplify Product Tool")
 	t.assetCatalog.ReadAssets(true)
-	t.productCatalog.ReadProducts()
+	if err := t.productCatalog.ReadProducts(); err != nil {
+		t.logger.WithError(err).Error("could not read products: stopping the tool")
+		return err
+	}
 	t.productCatalog.RepairProductWithBackup()
 	return nil
 }
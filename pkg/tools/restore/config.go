@@ -0,0 +1,10 @@
+package restore
+
+import "github.com/vivekschauhan/amplify-tool/pkg/tools"
+
+// Config the configuration for the Watch client
+type Config struct {
+	tools.Config
+	BackupFile string `mapstructure:"backup_file"`
+	Actions    string `mapstructure:"actions"`
+}
@@ -0,0 +1,200 @@
+package restore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Axway/agent-sdk/pkg/apic"
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	utillog "github.com/Axway/agent-sdk/pkg/util/log"
+	"github.com/sirupsen/logrus"
+	"github.com/vivekschauhan/amplify-tool/pkg/log"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+	"github.com/vivekschauhan/amplify-tool/pkg/tools"
+)
+
+const actionPrefix = "#\tACTION "
+
+type Tool interface {
+	Run() error
+}
+
+type tool struct {
+	apicClient apic.Client
+	cfg        *Config
+	logger     *logrus.Logger
+	failed     int
+}
+
+func NewTool(cfg *Config) Tool {
+	logger := log.GetLogger(cfg.Level, cfg.Format)
+	apicClient, _ := tools.CreateAPICClient(&cfg.Config)
+	utillog.GlobalLoggerConfig.Level(cfg.Level).
+		Format(cfg.Format).
+		Apply()
+	return &tool{
+		logger:     logger,
+		cfg:        cfg,
+		apicClient: apicClient,
+	}
+}
+
+func (t *tool) Run() error {
+	t.logger.Info("Amplify Duplicate Restore Tool")
+	backups, err := readBackup(t.cfg.BackupFile)
+	if err != nil {
+		t.logger.WithError(err).Error("could not read backup file: stopping the tool")
+		return err
+	}
+
+	actions, err := t.selectActions(backups)
+	if err != nil {
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+
+	for _, id := range actions {
+		logger := t.logger.WithField("action", id)
+		logger.WithField("services", len(backups[id])).Info("restoring action")
+		for _, svcInfo := range backups[id] {
+			t.restoreService(logger, svcInfo)
+		}
+	}
+
+	if t.failed > 0 {
+		err := fmt.Errorf("%d resources could not be restored", t.failed)
+		t.logger.WithError(err).Error("restore incomplete")
+		return err
+	}
+	t.logger.Info("restore finished")
+	return nil
+}
+
+// selectActions returns the IDs of the actions to restore, all actions in the backup when none are set
+func (t *tool) selectActions(backups map[string][]*service.APIServiceInfo) ([]string, error) {
+	actions := []string{}
+	for _, id := range strings.Split(t.cfg.Actions, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, found := backups[id]; !found {
+			return nil, fmt.Errorf("action %s is not in the backup file", id)
+		}
+		actions = append(actions, id)
+	}
+	if len(actions) > 0 {
+		return actions, nil
+	}
+	for id := range backups {
+		actions = append(actions, id)
+	}
+	sort.Strings(actions)
+	return actions, nil
+}
+
+// readBackup parses a backup file written by the duplicate or dedupeRevisions tool, the services of each action are on the line
+// following the action header
+func readBackup(fileName string) (map[string][]*service.APIServiceInfo, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	backups := map[string][]*service.APIServiceInfo{}
+	action := ""
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 256*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(scanner.Text(), actionPrefix):
+			action = strings.SplitN(strings.TrimPrefix(scanner.Text(), actionPrefix), ":", 2)[0]
+		case strings.HasPrefix(line, "["):
+			if action == "" {
+				return nil, fmt.Errorf("backup data found before an action header")
+			}
+			svcInfos := []*service.APIServiceInfo{}
+			if err := json.Unmarshal([]byte(line), &svcInfos); err != nil {
+				return nil, fmt.Errorf("unable to parse the backup of action %s: %s", action, err)
+			}
+			backups[action] = append(backups[action], svcInfos...)
+			action = ""
+		}
+	}
+	return backups, scanner.Err()
+}
+
+// restoreService re-creates the service, its revisions and then its instances with their original names
+func (t *tool) restoreService(logger *logrus.Entry, svcInfo *service.APIServiceInfo) {
+	if svcInfo == nil || svcInfo.APIService == nil {
+		return
+	}
+	logger = logger.WithField("env", svcInfo.APIService.Metadata.Scope.Name).WithField("service", svcInfo.APIService.Name)
+	if !t.restore(logger, svcInfo.APIService) {
+		logger.Error("skipping the revisions and instances of the service")
+		return
+	}
+
+	revNames := make([]string, 0, len(svcInfo.APIServiceRevisions))
+	for name := range svcInfo.APIServiceRevisions {
+		revNames = append(revNames, name)
+	}
+	sort.Strings(revNames)
+	for _, name := range revNames {
+		rev := svcInfo.APIServiceRevisions[name]
+		// revisions merged to the kept service are pointed back to the restored service
+		rev.Spec.ApiService = svcInfo.APIService.Name
+		t.restore(logger, rev)
+	}
+
+	instNames := make([]string, 0, len(svcInfo.APIServiceInstances))
+	for name := range svcInfo.APIServiceInstances {
+		instNames = append(instNames, name)
+	}
+	sort.Strings(instNames)
+	for _, name := range instNames {
+		t.restore(logger, svcInfo.APIServiceInstances[name])
+	}
+}
+
+// restore creates the resource, or updates it when it exists, and then its x-agent-details
+func (t *tool) restore(logger *logrus.Entry, res v1.Interface) bool {
+	ri, err := res.AsInstance()
+	if err != nil {
+		logger.WithError(err).Error("unable to read resource from backup")
+		t.failed++
+		return false
+	}
+	logger = logger.WithField("kind", ri.Kind).WithField("name", ri.Name)
+	existing, err := service.ExistingResource(t.apicClient, ri)
+	if err != nil {
+		logger.WithError(err).Error("unable to check the resource")
+		t.failed++
+		return false
+	}
+	exists := existing != nil
+	logger = logger.WithField("exists", exists)
+
+	service.ResetMetadata(ri)
+	if t.cfg.DryRun {
+		logger.Info("dry run, resource not restored")
+		return true
+	}
+
+	if exists {
+		ri.Metadata.ResourceVersion = existing.Metadata.ResourceVersion
+	}
+	if err := service.ApplyResource(t.apicClient, ri, exists); err != nil {
+		logger.WithError(err).Error("unable to restore resource")
+		t.failed++
+		return false
+	}
+	logger.Info("restored resource")
+	return true
+}
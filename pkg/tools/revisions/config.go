@@ -0,0 +1,12 @@
+package revisions
+
+import "github.com/vivekschauhan/amplify-tool/pkg/tools"
+
+// Config the configuration for the Watch client
+type Config struct {
+	tools.Config
+	Environments string `mapstructure:"environments"`
+	PlanFile     string `mapstructure:"plan_file"`
+	BackupFile   string `mapstructure:"backup_file"`
+	Execute      bool   `mapstructure:"execute"`
+}
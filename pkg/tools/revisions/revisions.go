@@ -0,0 +1,351 @@
+package revisions
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Axway/agent-sdk/pkg/apic"
+	catalog "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/catalog/v1alpha1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	"github.com/Axway/agent-sdk/pkg/apic/definitions"
+	"github.com/Axway/agent-sdk/pkg/util"
+	utillog "github.com/Axway/agent-sdk/pkg/util/log"
+	"github.com/sirupsen/logrus"
+	"github.com/vivekschauhan/amplify-tool/pkg/log"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+	"github.com/vivekschauhan/amplify-tool/pkg/tools"
+)
+
+type Tool interface {
+	Run() error
+}
+
+// revisionGroup is a set of revisions of a service with the same spec
+type revisionGroup struct {
+	Hash    string   `json:"hash"`
+	Kept    []string `json:"kept"`
+	Deleted []string `json:"deleted"`
+}
+
+// servicePlan is the plan to remove the identical revisions of a service, the ID is the action in the backup
+type servicePlan struct {
+	ID         string            `json:"id"`
+	Env        string            `json:"env"`
+	Service    string            `json:"service"`
+	Groups     []revisionGroup   `json:"groups"`
+	SpecHashes map[string]string `json:"specHashes,omitempty"`
+
+	svcInfo *service.APIServiceInfo
+	deleted []*management.APIServiceRevision
+}
+
+type tool struct {
+	apicClient      apic.Client
+	cfg             *Config
+	logger          *logrus.Logger
+	serviceRegistry service.ServiceRegistry
+	assetCatalog    service.AssetCatalog
+}
+
+func NewTool(cfg *Config) Tool {
+	logger := log.GetLogger(cfg.Level, cfg.Format)
+	apicClient, _ := tools.CreateAPICClient(&cfg.Config)
+	utillog.GlobalLoggerConfig.Level(cfg.Level).
+		Format(cfg.Format).
+		Apply()
+	envs := []string{}
+	for _, env := range strings.Split(cfg.Environments, ",") {
+		if env = strings.TrimSpace(env); env != "" {
+			envs = append(envs, env)
+		}
+	}
+	serviceRegistry := service.NewServiceRegistry(logger, apicClient, cfg.DryRun, service.WithGetInstances(), service.WithGetAllRevisions(true))
+	if len(envs) > 0 {
+		serviceRegistry = service.NewServiceRegistry(logger, apicClient, cfg.DryRun, service.WithGetInstances(), service.WithGetAllRevisions(true), service.WithEnvironments(envs))
+	}
+	assetCatalog := service.NewAssetCatalog(logger, apicClient, cfg.DryRun, serviceRegistry, service.WithFilterUsingRegistry(), service.ForExport(), service.WithExportAssetResources())
+	return &tool{
+		logger:          logger,
+		cfg:             cfg,
+		apicClient:      apicClient,
+		serviceRegistry: serviceRegistry,
+		assetCatalog:    assetCatalog,
+	}
+}
+
+func (t *tool) Run() error {
+	t.logger.Info("Amplify Revision Deduplication Tool")
+	if t.cfg.Execute && t.cfg.BackupFile == "" {
+		err := fmt.Errorf("a backup_file is required to execute the plan")
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+
+	t.serviceRegistry.ReadServices()
+	t.assetCatalog.ReadAssets(false)
+	mapped := t.mappedRevisions()
+
+	plans := []*servicePlan{}
+	deletes := 0
+	for _, env := range t.serviceRegistry.GetEnvs() {
+		for name, svcInfo := range t.serviceRegistry.GetAPIServicesInfo(env) {
+			svcInfo := svcInfo
+			if p := t.planService(env, name, &svcInfo, mapped); p != nil {
+				plans = append(plans, p)
+				deletes += len(p.deleted)
+			}
+		}
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		if plans[i].Env != plans[j].Env {
+			return plans[i].Env < plans[j].Env
+		}
+		return plans[i].Service < plans[j].Service
+	})
+	for i, p := range plans {
+		p.ID = fmt.Sprintf("%04d", i)
+	}
+	t.logger.WithField("services", len(plans)).WithField("revisions", deletes).Info("found identical revisions")
+
+	if t.cfg.PlanFile != "" {
+		service.SaveToFile(t.logger, "revision-plan", t.cfg.PlanFile, plans)
+	}
+	if !t.cfg.Execute || t.cfg.DryRun {
+		return nil
+	}
+
+	if err := writeBackup(t.cfg.BackupFile, plans); err != nil {
+		t.logger.WithError(err).Error("could not write backup file: stopping the tool")
+		return err
+	}
+	for _, p := range plans {
+		if err := t.execute(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mappedRevisions returns the group/env/name references of the revisions used by asset mappings
+func (t *tool) mappedRevisions() map[string]struct{} {
+	mapped := map[string]struct{}{}
+	for _, res := range t.assetCatalog.GetAssetOutput() {
+		ri, err := res.AsInstance()
+		if err != nil || ri.Kind != catalog.AssetMappingGVK().Kind {
+			continue
+		}
+		am := catalog.NewAssetMapping("", ri.Metadata.Scope.Name)
+		am.FromInstance(ri)
+		if am.Spec.Inputs.ApiServiceRevision != "" {
+			mapped[revisionKey(am.Spec.Inputs.ApiServiceRevision)] = struct{}{}
+		}
+	}
+	return mapped
+}
+
+// writeBackup saves the deleted revisions, with their service as it was before its specHashes were updated, in the
+// backup format of the duplicate tool, so that restoreDuplicates can restore them by the plan ID
+func writeBackup(fileName string, plans []*servicePlan) error {
+	lines := []string{}
+	for _, p := range plans {
+		svcInfo := &service.APIServiceInfo{
+			APIService:          p.svcInfo.APIService,
+			APIServiceRevisions: map[string]*management.APIServiceRevision{},
+			APIServiceInstances: map[string]*management.APIServiceInstance{},
+		}
+		for _, rev := range p.deleted {
+			svcInfo.APIServiceRevisions[rev.Name] = rev
+		}
+		buf, err := json.Marshal([]*service.APIServiceInfo{svcInfo})
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("#\tACTION %s: Deleted revisions of service %s/%s", p.ID, p.Env, p.Service), string(buf), "")
+	}
+	return os.WriteFile(fileName, []byte(strings.Join(lines, "\n")), 0777)
+}
+
+// revisionKey returns the env/name of a revision reference in either the group/env/name or env/name form
+func revisionKey(ref string) string {
+	elements := strings.Split(ref, "/")
+	if len(elements) < 2 {
+		return ref
+	}
+	return strings.Join(elements[len(elements)-2:], "/")
+}
+
+// specHash returns the SHA-256 of the decoded spec of the revision
+func specHash(rev *management.APIServiceRevision) string {
+	if rev.Spec.Definition.Value == "" {
+		return ""
+	}
+	spec, err := base64.StdEncoding.DecodeString(rev.Spec.Definition.Value)
+	if err != nil {
+		spec = []byte(rev.Spec.Definition.Value)
+	}
+	sum := sha256.Sum256(spec)
+	return hex.EncodeToString(sum[:])
+}
+
+// planService groups the revisions of the service by spec and plans deleting each revision that is not referenced
+// by an instance, asset mapping or asset resource, keeping at least one of each spec. The specHashes of the service that point to a
+// deleted revision are pointed to the kept revision with the same spec.
+func (t *tool) planService(env, name string, svcInfo *service.APIServiceInfo, mapped map[string]struct{}) *servicePlan {
+	logger := t.logger.WithField("env", env).WithField("service", name)
+	referenced := map[string]struct{}{}
+	for _, inst := range svcInfo.APIServiceInstances {
+		referenced[inst.Spec.ApiServiceRevision] = struct{}{}
+	}
+	for _, rev := range svcInfo.APIServiceRevisions {
+		if _, found := mapped[fmt.Sprintf("%s/%s", env, rev.Name)]; found {
+			referenced[rev.Name] = struct{}{}
+		}
+		// the asset resources of published asset releases still reference the revision
+		if len(t.assetCatalog.AssetsForRevision(rev.Group, env, rev.Name)) > 0 {
+			referenced[rev.Name] = struct{}{}
+		}
+	}
+	specHashes := serviceSpecHashes(svcInfo.APIService)
+
+	groups := map[string][]*management.APIServiceRevision{}
+	for _, rev := range svcInfo.APIServiceRevisions {
+		if hash := specHash(rev); hash != "" {
+			groups[hash] = append(groups[hash], rev)
+		}
+	}
+
+	p := &servicePlan{
+		Env:        env,
+		Service:    name,
+		Groups:     []revisionGroup{},
+		SpecHashes: map[string]string{},
+		svcInfo:    svcInfo,
+		deleted:    []*management.APIServiceRevision{},
+	}
+	keptByHash := map[string]string{}
+	hashOf := map[string]string{}
+	for hash, revs := range groups {
+		for _, rev := range revs {
+			hashOf[rev.Name] = hash
+		}
+		if len(revs) <= 1 {
+			keptByHash[hash] = revs[0].Name
+			continue
+		}
+
+		// newest first, so that the newest revision is kept when none are referenced
+		sort.Slice(revs, func(i, j int) bool {
+			return time.Time(revs[i].Metadata.Audit.CreateTimestamp).After(time.Time(revs[j].Metadata.Audit.CreateTimestamp))
+		})
+		group := revisionGroup{Hash: hash, Kept: []string{}, Deleted: []string{}}
+		for _, rev := range revs {
+			if _, found := referenced[rev.Name]; found {
+				group.Kept = append(group.Kept, rev.Name)
+			}
+		}
+		if len(group.Kept) == 0 {
+			group.Kept = append(group.Kept, revs[0].Name)
+		}
+		sort.Strings(group.Kept)
+		keptByHash[hash] = group.Kept[0]
+		for _, rev := range revs {
+			if !contains(group.Kept, rev.Name) {
+				group.Deleted = append(group.Deleted, rev.Name)
+				p.deleted = append(p.deleted, rev)
+			}
+		}
+		sort.Strings(group.Deleted)
+		if len(group.Deleted) > 0 {
+			p.Groups = append(p.Groups, group)
+		}
+	}
+	if len(p.deleted) == 0 {
+		return nil
+	}
+
+	// keep the specHashes pointing at revisions that still exist
+	deleted := deletedNames(p.deleted)
+	for agentHash, revName := range specHashes {
+		if contains(deleted, revName) {
+			p.SpecHashes[agentHash] = keptByHash[hashOf[revName]]
+		}
+	}
+	sort.Slice(p.Groups, func(i, j int) bool {
+		return p.Groups[i].Hash < p.Groups[j].Hash
+	})
+	logger.WithField("revisions", len(svcInfo.APIServiceRevisions)).WithField("delete", len(p.deleted)).Info("planned revision clean up")
+	return p
+}
+
+// execute deletes the revisions of the plan and then updates the specHashes of the service, stopping on the first failure
+func (t *tool) execute(p *servicePlan) error {
+	logger := t.logger.WithField("env", p.Env).WithField("service", p.Service)
+	for _, rev := range p.deleted {
+		if err := t.apicClient.DeleteResourceInstance(rev); err != nil {
+			logger.WithError(err).WithField("revision", rev.Name).Error("unable to delete revision, stopping execution")
+			return err
+		}
+		logger.WithField("revision", rev.Name).Info("deleted revision")
+	}
+	if len(p.SpecHashes) == 0 {
+		return nil
+	}
+
+	svc := p.svcInfo.APIService
+	details := util.GetAgentDetails(svc)
+	specHashes := serviceSpecHashes(svc)
+	for agentHash, revName := range p.SpecHashes {
+		specHashes[agentHash] = revName
+	}
+	hashes := map[string]interface{}{}
+	for agentHash, revName := range specHashes {
+		hashes[agentHash] = revName
+	}
+	details["specHashes"] = hashes
+	err := t.apicClient.CreateSubResource(svc.ResourceMeta, map[string]interface{}{definitions.XAgentDetails: details})
+	if err != nil {
+		logger.WithError(err).Error("unable to update the specHashes of the service, stopping execution")
+		return err
+	}
+	logger.Info("updated specHashes of service")
+	return nil
+}
+
+// serviceSpecHashes returns the specHashes x-agent-detail of the service, agent hash to revision name
+func serviceSpecHashes(svc *management.APIService) map[string]string {
+	specHashes := map[string]string{}
+	if v, found := util.GetAgentDetails(svc)["specHashes"]; found {
+		if hashes, ok := v.(map[string]interface{}); ok {
+			for hash, revName := range hashes {
+				if name, ok := revName.(string); ok {
+					specHashes[hash] = name
+				}
+			}
+		}
+	}
+	return specHashes
+}
+
+func deletedNames(revs []*management.APIServiceRevision) []string {
+	names := make([]string, 0, len(revs))
+	for _, rev := range revs {
+		names = append(names, rev.Name)
+	}
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+package rollback
+
+import "github.com/vivekschauhan/amplify-tool/pkg/tools"
+
+// Config the configuration for the Watch client
+type Config struct {
+	tools.Config
+	Journal string `mapstructure:"journal"`
+}
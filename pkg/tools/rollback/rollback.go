@@ -0,0 +1,180 @@
+package rollback
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Axway/agent-sdk/pkg/apic"
+	utillog "github.com/Axway/agent-sdk/pkg/util/log"
+	"github.com/sirupsen/logrus"
+	"github.com/vivekschauhan/amplify-tool/pkg/log"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+	"github.com/vivekschauhan/amplify-tool/pkg/tools"
+)
+
+type Tool interface {
+	Run() error
+}
+
+type tool struct {
+	apicClient apic.Client
+	cfg        *Config
+	logger     *logrus.Logger
+}
+
+func NewTool(cfg *Config) Tool {
+	logger := log.GetLogger(cfg.Level, cfg.Format)
+	apicClient, _ := tools.CreateAPICClient(&cfg.Config)
+	utillog.GlobalLoggerConfig.Level(cfg.Level).
+		Format(cfg.Format).
+		Apply()
+	return &tool{
+		logger:     logger,
+		cfg:        cfg,
+		apicClient: apicClient,
+	}
+}
+
+func (t *tool) Run() error {
+	t.logger.Info("Amplify Rollback Tool")
+	if t.cfg.Journal == "" {
+		err := fmt.Errorf("a journal is required")
+		t.logger.WithError(err).Error("stopping the tool")
+		return err
+	}
+	entries, err := service.ReadJournal(t.cfg.Journal)
+	if err != nil {
+		t.logger.WithError(err).Error("could not read journal: stopping the tool")
+		return err
+	}
+	t.logger.WithField("changes", len(entries)).Info("rolling back journal")
+
+	// the first state change of each resource, the later changes are superseded when the resource is recreated
+	firstState := map[string]int{}
+	for i, entry := range entries {
+		if _, found := firstState[entryKey(entry)]; !found && entry.Operation == service.JournalState && entry.RolledBack == nil {
+			firstState[entryKey(entry)] = i
+		}
+	}
+
+	// undo the changes in the reverse of the order they were made
+	notReversed := 0
+	lost := []string{}
+	recreated := map[string]bool{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := &entries[i]
+		logger := t.logger.
+			WithField("time", entry.Time).
+			WithField("operation", entry.Operation).
+			WithField("kind", entry.Kind).
+			WithField("scope", entry.Scope).
+			WithField("name", entry.Name)
+		if entry.RolledBack != nil {
+			logger.WithField("rolledBack", entry.RolledBack).Debug("change was already rolled back")
+			continue
+		}
+		if entry.Operation == service.JournalLost {
+			logger.WithField("reason", entry.Reason).Warn("change can not be reversed")
+			lost = append(lost, fmt.Sprintf("%s %s/%s: %s", entry.Kind, entry.Scope, entry.Name, entry.Reason))
+			continue
+		}
+		if entry.Operation == service.JournalState && recreated[entryKey(*entry)] && firstState[entryKey(*entry)] != i {
+			logger.Info("state change superseded by recreating the resource")
+			t.markRolledBack(entry)
+			continue
+		}
+		if err := t.reverse(logger, *entry); err != nil {
+			logger.WithError(err).Warn("could not reverse change")
+			notReversed++
+			continue
+		}
+		if entry.Operation == service.JournalDelete {
+			recreated[entryKey(*entry)] = true
+		}
+		t.markRolledBack(entry)
+		logger.Info("reversed change")
+	}
+
+	if !t.cfg.DryRun {
+		if err := service.WriteJournal(t.cfg.Journal, entries); err != nil {
+			t.logger.WithError(err).Error("could not mark the reversed changes in the journal")
+			return err
+		}
+	}
+
+	for _, l := range lost {
+		t.logger.WithField("lost", l).Warn("not restored by the rollback")
+	}
+	if notReversed > 0 || len(lost) > 0 {
+		err := fmt.Errorf("%d changes could not be reversed and %d changes lost resources that are not restored", notReversed, len(lost))
+		t.logger.WithError(err).Error("rollback incomplete, review the changes that could not be reversed")
+		return err
+	}
+	t.logger.Info("rollback finished")
+	return nil
+}
+
+// entryKey identifies the resource of a journal entry by kind/scope/name
+func entryKey(entry service.JournalEntry) string {
+	return fmt.Sprintf("%s/%s/%s", entry.Kind, entry.Scope, entry.Name)
+}
+
+// markRolledBack marks the entry so that a later rollback of the journal does not reverse it again
+func (t *tool) markRolledBack(entry *service.JournalEntry) {
+	if t.cfg.DryRun {
+		return
+	}
+	now := time.Now().UTC()
+	entry.RolledBack = &now
+}
+
+// reverse undoes a single change, deleting created resources, creating deleted resources and restoring previous
+// resources and states
+func (t *tool) reverse(logger *logrus.Entry, entry service.JournalEntry) error {
+	switch entry.Operation {
+	case service.JournalCreate:
+		if entry.After == nil {
+			return fmt.Errorf("the created resource is not in the journal")
+		}
+		logger.Info("deleting created resource")
+		if t.cfg.DryRun {
+			return nil
+		}
+		return t.apicClient.DeleteResourceInstance(entry.After)
+	case service.JournalDelete:
+		if entry.Before == nil {
+			return fmt.Errorf("the deleted resource is not in the journal")
+		}
+		logger.Info("recreating deleted resource")
+		if t.cfg.DryRun {
+			return nil
+		}
+		ri := entry.Before
+		service.ResetMetadata(ri)
+		_, err := t.apicClient.CreateResourceInstance(ri)
+		return err
+	case service.JournalUpdate:
+		if entry.Before == nil {
+			return fmt.Errorf("the resource before the update is not in the journal")
+		}
+		logger.Info("restoring updated resource")
+		if t.cfg.DryRun {
+			return nil
+		}
+		ri := entry.Before
+		ri.Metadata.ResourceVersion = ""
+		_, err := t.apicClient.UpdateResourceInstance(ri)
+		return err
+	case service.JournalState:
+		if entry.Before == nil || entry.StateBefore == "" {
+			return fmt.Errorf("the previous state is not in the journal")
+		}
+		logger = logger.WithField("state", entry.StateBefore)
+		logger.Info("restoring previous state")
+		if t.cfg.DryRun {
+			return nil
+		}
+		return t.apicClient.CreateSubResource(entry.Before.ResourceMeta, map[string]interface{}{"state": entry.StateBefore})
+	}
+	return fmt.Errorf("unknown operation %q", entry.Operation)
+}
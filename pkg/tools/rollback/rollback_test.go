@@ -0,0 +1,196 @@
+package rollback
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Axway/agent-sdk/pkg/apic"
+	v1 "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/api/v1"
+	management "github.com/Axway/agent-sdk/pkg/apic/apiserver/models/management/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+)
+
+// fakeClient records the calls that reverse the changes, the calls for the resources in fail return an error
+type fakeClient struct {
+	apic.Client
+	calls []string
+	fail  map[string]bool
+}
+
+func (c *fakeClient) call(call, name string) error {
+	c.calls = append(c.calls, call)
+	if c.fail[name] {
+		return fmt.Errorf("%s failed", call)
+	}
+	return nil
+}
+
+func (c *fakeClient) CreateResourceInstance(ri v1.Interface) (*v1.ResourceInstance, error) {
+	return nil, c.call("create "+ri.GetName(), ri.GetName())
+}
+
+func (c *fakeClient) UpdateResourceInstance(ri v1.Interface) (*v1.ResourceInstance, error) {
+	return nil, c.call("update "+ri.GetName(), ri.GetName())
+}
+
+func (c *fakeClient) DeleteResourceInstance(ri v1.Interface) error {
+	return c.call("delete "+ri.GetName(), ri.GetName())
+}
+
+func (c *fakeClient) CreateSubResource(rm v1.ResourceMeta, subs map[string]interface{}) error {
+	return c.call(fmt.Sprintf("state %s %v", rm.Name, subs["state"]), rm.Name)
+}
+
+func journalEntry(t *testing.T, op service.JournalOperation, name, stateBefore string) service.JournalEntry {
+	t.Helper()
+	ri, err := management.NewAPIService(name, "env").AsInstance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := service.JournalEntry{Operation: op, Kind: ri.Kind, Scope: "env", Name: name, StateBefore: stateBefore}
+	switch op {
+	case service.JournalCreate:
+		entry.After = ri
+	case service.JournalLost:
+		entry.Reason = "subscriptions removed"
+	default:
+		entry.Before = ri
+	}
+	return entry
+}
+
+func TestRunOrder(t *testing.T) {
+	rolledBack := time.Now().UTC()
+	tests := []struct {
+		name           string
+		entries        func(t *testing.T) []service.JournalEntry
+		fail           map[string]bool
+		wantCalls      []string
+		wantRolledBack []bool
+		wantErr        bool
+	}{
+		{
+			name: "newest change first",
+			entries: func(t *testing.T) []service.JournalEntry {
+				return []service.JournalEntry{
+					journalEntry(t, service.JournalCreate, "a", ""),
+					journalEntry(t, service.JournalUpdate, "b", ""),
+					journalEntry(t, service.JournalDelete, "c", ""),
+					journalEntry(t, service.JournalState, "d", "active"),
+				}
+			},
+			wantCalls:      []string{"state d active", "create c", "update b", "delete a"},
+			wantRolledBack: []bool{true, true, true, true},
+		},
+		{
+			name: "state changes superseded by recreating the resource",
+			entries: func(t *testing.T) []service.JournalEntry {
+				return []service.JournalEntry{
+					journalEntry(t, service.JournalState, "p", "active"),
+					journalEntry(t, service.JournalState, "p", "deprecated"),
+					journalEntry(t, service.JournalDelete, "p", ""),
+				}
+			},
+			wantCalls:      []string{"create p", "state p active"},
+			wantRolledBack: []bool{true, true, true},
+		},
+		{
+			name: "state changes without a recreate are all reversed",
+			entries: func(t *testing.T) []service.JournalEntry {
+				return []service.JournalEntry{
+					journalEntry(t, service.JournalState, "p", "active"),
+					journalEntry(t, service.JournalState, "p", "deprecated"),
+				}
+			},
+			wantCalls:      []string{"state p deprecated", "state p active"},
+			wantRolledBack: []bool{true, true},
+		},
+		{
+			name: "changes already rolled back are skipped",
+			entries: func(t *testing.T) []service.JournalEntry {
+				entries := []service.JournalEntry{
+					journalEntry(t, service.JournalCreate, "a", ""),
+					journalEntry(t, service.JournalCreate, "b", ""),
+				}
+				entries[1].RolledBack = &rolledBack
+				return entries
+			},
+			wantCalls:      []string{"delete a"},
+			wantRolledBack: []bool{true, true},
+		},
+		{
+			name: "a failed change does not stop the rollback",
+			entries: func(t *testing.T) []service.JournalEntry {
+				return []service.JournalEntry{
+					journalEntry(t, service.JournalCreate, "a", ""),
+					journalEntry(t, service.JournalCreate, "fail", ""),
+					journalEntry(t, service.JournalCreate, "c", ""),
+				}
+			},
+			fail:           map[string]bool{"fail": true},
+			wantCalls:      []string{"delete c", "delete fail", "delete a"},
+			wantRolledBack: []bool{true, false, true},
+			wantErr:        true,
+		},
+		{
+			name: "lost changes are reported",
+			entries: func(t *testing.T) []service.JournalEntry {
+				return []service.JournalEntry{
+					journalEntry(t, service.JournalCreate, "a", ""),
+					journalEntry(t, service.JournalLost, "plan", ""),
+				}
+			},
+			wantCalls:      []string{"delete a"},
+			wantRolledBack: []bool{true, false},
+			wantErr:        true,
+		},
+		{
+			name: "unknown previous state",
+			entries: func(t *testing.T) []service.JournalEntry {
+				return []service.JournalEntry{
+					journalEntry(t, service.JournalState, "p", ""),
+				}
+			},
+			wantCalls:      []string{},
+			wantRolledBack: []bool{false},
+			wantErr:        true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			journal := filepath.Join(t.TempDir(), "journal.json")
+			if err := service.WriteJournal(journal, tc.entries(t)); err != nil {
+				t.Fatal(err)
+			}
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			client := &fakeClient{calls: []string{}, fail: tc.fail}
+			rt := &tool{apicClient: client, cfg: &Config{Journal: journal}, logger: logger}
+
+			err := rt.Run()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(client.calls, tc.wantCalls) {
+				t.Errorf("got calls %q, want %q", client.calls, tc.wantCalls)
+			}
+
+			entries, err := service.ReadJournal(journal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []bool{}
+			for _, entry := range entries {
+				got = append(got, entry.RolledBack != nil)
+			}
+			if !reflect.DeepEqual(got, tc.wantRolledBack) {
+				t.Errorf("got rolled back %v, want %v", got, tc.wantRolledBack)
+			}
+		})
+	}
+}
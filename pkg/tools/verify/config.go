@@ -0,0 +1,10 @@
+package verify
+
+import "github.com/vivekschauhan/amplify-tool/pkg/tools"
+
+// Config the configuration for the Watch client
+type Config struct {
+	tools.Config
+	InFile       string `mapstructure:"in_file"`
+	ManifestFile string `mapstructure:"manifest_file"`
+}
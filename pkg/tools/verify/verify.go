@@ -0,0 +1,57 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vivekschauhan/amplify-tool/pkg/log"
+	"github.com/vivekschauhan/amplify-tool/pkg/service"
+)
+
+type Tool interface {
+	Run() error
+}
+
+type tool struct {
+	cfg    *Config
+	logger *logrus.Logger
+}
+
+func NewTool(cfg *Config) Tool {
+	return &tool{
+		logger: log.GetLogger(cfg.Level, cfg.Format),
+		cfg:    cfg,
+	}
+}
+
+func (t *tool) Run() error {
+	t.logger.Info("Amplify Export Verify Tool")
+	manifestFile := t.cfg.ManifestFile
+	if manifestFile == "" {
+		manifestFile = service.ManifestFileName(t.cfg.InFile)
+	}
+	logger := t.logger.WithField("file", t.cfg.InFile).WithField("manifest", manifestFile)
+
+	manifest, err := service.ReadManifest(manifestFile)
+	if err != nil {
+		logger.WithError(err).Error("unable to read the manifest")
+		return err
+	}
+	problems, err := service.VerifyManifest(manifest, t.cfg.InFile)
+	if err != nil {
+		logger.WithError(err).Error("unable to verify the export")
+		return err
+	}
+	for _, problem := range problems {
+		logger.Error(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("export %s does not match its manifest", t.cfg.InFile)
+	}
+
+	logger.
+		WithField("environments", manifest.Environments).
+		WithField("counts", manifest.Counts).
+		Info("export matches its manifest")
+	return nil
+}